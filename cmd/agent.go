@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+var agentStdio bool
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Serve local metrics to a gosysmesh controller",
+	Long: `Agent runs on a remote host, started by the controller over SSH for
+targets configured with "mode: agent". It collects system stats and processes
+with the same collectors as local monitoring and answers the controller's
+requests as framed JSON on stdin and stdout.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if !agentStdio {
+			return errors.New("the agent only supports --stdio")
+		}
+		return agent.Serve(cmd.InOrStdin(), cmd.OutOrStdout(), agent.LocalCollectors())
+	},
+}
+
+func init() {
+	agentCmd.Flags().BoolVar(&agentStdio, "stdio", false, "speak the agent protocol on stdin and stdout")
+}
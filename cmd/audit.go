@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/audit"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditTailLines  int
+	auditTailFollow bool
+	auditTailJSON   bool
+	auditTailFile   string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of remote commands",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print the most recent audit log entries",
+	Long: `Tail prints the last entries of the audit log configured under audit.path,
+one line per remote command. With --follow it keeps printing entries as they
+are appended, across rotations.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		path := auditTailFile
+		if path == "" {
+			conf, err := config.LoadConfig(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !conf.Audit.Enabled() {
+				return errors.New("audit logging is not configured (set audit.path or use --file)")
+			}
+			path = conf.Audit.Path
+		}
+
+		out := cmd.OutOrStdout()
+		lines, offset, err := audit.Tail(path, auditTailLines)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			printAuditLine(out, line)
+		}
+		if !auditTailFollow {
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		return audit.Follow(ctx, path, offset, func(line string) {
+			printAuditLine(out, line)
+		})
+	},
+}
+
+// printAuditLine prints one audit log line, formatted unless --json is set.
+func printAuditLine(out io.Writer, line string) {
+	var e audit.Entry
+	if auditTailJSON || json.Unmarshal([]byte(line), &e) != nil {
+		fmt.Fprintln(out, line)
+		return
+	}
+
+	route := sshAddress(e.User, e.Host, e.Port)
+	if len(e.JumpHosts) > 0 {
+		route += " via " + strings.Join(e.JumpHosts, " → ")
+	}
+	status := fmt.Sprintf("%sexit %d%s", green, e.ExitStatus, reset)
+	if e.Error != "" {
+		status = fmt.Sprintf("%sexit %d %s%s", red, e.ExitStatus, e.ErrorClass, reset)
+	}
+	fmt.Fprintf(out, "%s  %-15s %s  %s  %dms  %dB\n",
+		e.Time.Local().Format("2006-01-02 15:04:05"), e.CommandID, route, status, e.DurationMS, e.BytesOut)
+}
+
+// openAuditLog starts auditing remote commands if the config enables it,
+// replacing any previous audit log, and returns a function that closes the
+// new one.
+func openAuditLog(conf *config.Config) (func(), error) {
+	if !conf.Audit.Enabled() {
+		remote.SetAuditLogger(nil)
+		return func() {}, nil
+	}
+	l, err := audit.Open(conf.Audit.Path, conf.Audit.MaxSizeBytes(), conf.Audit.Backups())
+	if err != nil {
+		return nil, err
+	}
+	remote.SetAuditLogger(l)
+	return func() { l.Close() }, nil
+}
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "Number of entries to print")
+	auditTailCmd.Flags().BoolVarP(&auditTailFollow, "follow", "f", false, "Keep printing new entries as they are written")
+	auditTailCmd.Flags().BoolVar(&auditTailJSON, "json", false, "Print raw JSON lines")
+	auditTailCmd.Flags().StringVar(&auditTailFile, "file", "", "Audit log to read instead of the configured one")
+	auditCmd.AddCommand(auditTailCmd)
+}
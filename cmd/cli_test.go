@@ -1,10 +1,22 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ChristianThibeault/gosysmesh/internal/agent"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/ChristianThibeault/gosysmesh/internal/schedule"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHelpCommand(t *testing.T) {
@@ -20,3 +32,255 @@ func TestHelpCommand(t *testing.T) {
 	assert.Contains(t, output, "gosysmesh")
 }
 
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "-"},
+		{42 * time.Second, "42s"},
+		{12*time.Minute + 5*time.Second, "12m5s"},
+		{5*time.Hour + 12*time.Minute + 30*time.Second, "5h12m"},
+		{3*24*time.Hour + 4*time.Hour + 59*time.Minute, "3d4h"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, formatAge(tt.in))
+	}
+}
+
+func TestConfigHolderKeepsPreviousOnInvalidReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gosysmesh.yaml")
+	valid := "interval: \"5s\"\nmonitor:\n  local:\n    process_filters:\n      keywords: [\"go\"]\n"
+	require.NoError(t, os.WriteFile(path, []byte(valid), 0o600))
+
+	conf, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	holder := newConfigHolder(path, conf)
+
+	require.NoError(t, os.WriteFile(path, []byte("interval: \"0s\"\n"), 0o600))
+	_, err = holder.Reload()
+	assert.Error(t, err)
+	assert.Same(t, conf, holder.Load())
+
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(valid, "5s", "10s", 1)), 0o600))
+	reloaded, err := holder.Reload()
+	require.NoError(t, err)
+	assert.Same(t, reloaded, holder.Load())
+	assert.Equal(t, "10s", holder.Load().Interval)
+}
+
+func TestConfigShowAndValidate(t *testing.T) {
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	defer rootCmd.SetArgs(nil)
+
+	rootCmd.SetArgs([]string{"config", "show", "--config", "../test_configs/groups.yaml"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, buf.String(), "host: db1.example.com")
+	assert.Contains(t, buf.String(), "port: 2222")
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"config", "validate", "--config", "../test_configs/multiple_errors.yaml"})
+	err := rootCmd.Execute()
+	assert.ErrorContains(t, err, "8 problem(s)")
+	assert.Contains(t, buf.String(), "monitor.remote[1].group (line 9)")
+}
+
+func TestConfigShowOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&buf)
+	defer func() {
+		rootCmd.SetArgs(nil)
+		setArgs = nil
+		rootCmd.PersistentFlags().Lookup("set").Changed = false
+		require.NoError(t, config.SetFlagOverrides(nil))
+	}()
+
+	t.Setenv("GOSYSMESH_INTERVAL", "10s")
+	t.Setenv("GOSYSMESH_MONITOR_REMOTE_0_PORT", "2200")
+	rootCmd.SetArgs([]string{"config", "show", "--config", "../test_configs/groups.yaml", "--set", "monitor.remote[0].port=2201"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, buf.String(), "interval: 10s")
+	assert.Contains(t, buf.String(), "port: 2201", "--set wins over the environment")
+}
+
+func TestConfigInitInteractive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosysmesh.yaml")
+	initOpts.output, initOpts.skipSSHTest, initOpts.interval = path, true, "30s"
+	defer func() { initOpts.output, initOpts.skipSSHTest = "", false }()
+
+	answers := strings.Join([]string{
+		"10s",             // interval
+		"nginx, postgres", // local keywords
+		"admin@db1.example.com:2222",
+		"",              // finish host list
+		"~/.ssh/db_key", // key for db1
+		"",              // no jump host
+		"postgres",      // keywords for db1
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	require.NoError(t, runConfigInit(strings.NewReader(answers), &out))
+	assert.Contains(t, out.String(), "Wrote "+path)
+
+	conf, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "10s", conf.Interval)
+	assert.Equal(t, []string{"nginx", "postgres"}, conf.Monitor.Local.ProcessFilters.Keywords)
+	require.Len(t, conf.Monitor.Remote, 1)
+	assert.Equal(t, config.RemoteTarget{
+		Host:           "db1.example.com",
+		User:           "admin",
+		Port:           2222,
+		SSHKey:         "~/.ssh/db_key",
+		ProcessFilters: config.ProcessFilterConfig{Keywords: []string{"postgres"}},
+	}, conf.Monitor.Remote[0])
+
+	// refuses to overwrite without --force when non-interactive
+	initOpts.nonInteractive = true
+	defer func() { initOpts.nonInteractive = false }()
+	assert.Error(t, runConfigInit(strings.NewReader(""), &out))
+}
+
+func TestConfigInitNonInteractive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosysmesh.yaml")
+	initOpts.output, initOpts.skipSSHTest, initOpts.nonInteractive = path, true, true
+	initOpts.hosts, initOpts.sshKey, initOpts.keywords = []string{"deploy@web1.example.com", "ops@web2.example.com"}, "~/.ssh/deploy", []string{"node"}
+	defer func() {
+		initOpts.output, initOpts.skipSSHTest, initOpts.nonInteractive = "", false, false
+		initOpts.hosts, initOpts.sshKey, initOpts.keywords = nil, "", nil
+	}()
+
+	var out bytes.Buffer
+	require.NoError(t, runConfigInit(strings.NewReader(""), &out))
+
+	conf, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, conf.Monitor.Remote, 2)
+	assert.Equal(t, "ops", conf.Monitor.Remote[1].User)
+	assert.Equal(t, 22, conf.Monitor.Remote[1].Port)
+	assert.Equal(t, "~/.ssh/deploy", conf.Monitor.Remote[1].SSHKey)
+	assert.Equal(t, []string{"node"}, conf.Monitor.Remote[0].ProcessFilters.Keywords)
+}
+
+func TestRunRestricted(t *testing.T) {
+	t.Setenv("SSH_CLIENT", "")
+	var logBuf bytes.Buffer
+	logger := log.New(&logBuf, "", 0)
+
+	t.Run("rejects and logs unknown commands", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runRestricted("cat /etc/shadow", strings.NewReader(""), &stdout, &stderr, logger)
+		assert.Equal(t, 1, code)
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "not allowed")
+		assert.Contains(t, logBuf.String(), `rejected command from "": "cat /etc/shadow"`)
+	})
+
+	t.Run("rejects registered commands that change the host", func(t *testing.T) {
+		remove, err := remote.BuildCommand(remote.CommandRemove, ".gosysmesh/bin/gosysmesh")
+		require.NoError(t, err)
+		var stdout, stderr bytes.Buffer
+		code := runRestricted(remove, strings.NewReader(""), &stdout, &stderr, logger)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, logBuf.String(), "rejected deploy-remove command")
+	})
+
+	t.Run("rejects registered commands that run a given binary", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runRestricted("'/bin/sh' version", strings.NewReader(""), &stdout, &stderr, logger)
+		assert.Equal(t, 1, code)
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, logBuf.String(), "rejected agent-version command")
+	})
+
+	t.Run("runs allowed commands", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		probe, err := remote.BuildCommand(remote.CommandProbe)
+		require.NoError(t, err)
+		code := runRestricted(probe, strings.NewReader(""), &stdout, &stderr, logger)
+		assert.Equal(t, 0, code)
+		assert.NotEmpty(t, strings.TrimSpace(stdout.String()))
+		assert.Contains(t, logBuf.String(), "allowed probe command")
+	})
+
+	t.Run("serves the agent itself", func(t *testing.T) {
+		var stdin, stdout, stderr bytes.Buffer
+		require.NoError(t, agent.WriteFrame(&stdin, agent.Request{Method: agent.MethodHello}))
+		command, err := remote.BuildCommand(remote.CommandAgent, "/nonexistent/gosysmesh")
+		require.NoError(t, err)
+		code := runRestricted(command, &stdin, &stdout, &stderr, logger)
+		assert.Equal(t, 0, code)
+
+		var resp agent.Response
+		require.NoError(t, agent.ReadFrame(bufio.NewReader(&stdout), &resp))
+		assert.Equal(t, agent.ProtocolVersion, resp.Protocol)
+	})
+}
+
+func TestScheduledCollectionCombinesFamilies(t *testing.T) {
+	conf, err := config.LoadConfig("../test_configs/groups.yaml")
+	require.NoError(t, err)
+	conf.Monitor.Local.Enabled = false
+
+	calls := map[string][][]config.MetricFamily{}
+	orig := collectRemote
+	t.Cleanup(func() { collectRemote = orig })
+	collectRemote = func(target config.RemoteTarget, families []config.MetricFamily) (*remote.RemoteMetrics, error) {
+		calls[target.Host] = append(calls[target.Host], families)
+		return &remote.RemoteMetrics{Host: target.Host, Families: families, Timestamp: time.Now()}, nil
+	}
+
+	// run drives the scheduler through three 30s cycles
+	run := func() {
+		start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+		sched := schedule.New(rand.New(rand.NewSource(1)))
+		sched.Set(scheduleJobs(conf), start)
+		for {
+			next, ok := sched.Next()
+			require.True(t, ok)
+			if !next.Before(start.Add(90 * time.Second)) {
+				return
+			}
+			runMonitoring(conf, dueKeys(sched.Due(next)))
+		}
+	}
+
+	t.Run("one round-trip per target and cycle", func(t *testing.T) {
+		clear(calls)
+		run()
+		require.Len(t, calls, 4)
+		for host, families := range calls {
+			assert.Equal(t, [][]config.MetricFamily{config.MetricFamilies, config.MetricFamilies, config.MetricFamilies}, families, host)
+		}
+	})
+
+	t.Run("a family on its own interval", func(t *testing.T) {
+		clear(calls)
+		conf.Intervals = map[string]string{"disk": "5m"}
+		defer func() { conf.Intervals = nil }()
+		run()
+		require.Len(t, calls, 4)
+		frequent := []config.MetricFamily{config.FamilyProcesses, config.FamilySystem}
+		for host, families := range calls {
+			assert.ElementsMatch(t, [][]config.MetricFamily{frequent, frequent, frequent, {config.FamilyDisk}}, families, host)
+		}
+	})
+
+	t.Run("a jitter larger than the interval", func(t *testing.T) {
+		clear(calls)
+		conf.Interval, conf.Jitter = "5s", "10m"
+		defer func() { conf.Interval, conf.Jitter = "30s", "" }()
+		run()
+		require.Len(t, calls, 4)
+		for host, families := range calls {
+			// Still every 5s: the jitter is capped at the interval
+			assert.Len(t, families, 18, host)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd groups commands that inspect the configuration
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Report every problem in the configuration",
+	Long: `Validate loads the configuration exactly as start does and lists every
+problem found, with the YAML path and line of the offending value.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		conf, err := config.LoadConfig(cfgFile)
+		path := viper.ConfigFileUsed()
+		if err != nil {
+			var verrs config.ValidationErrors
+			if !errors.As(err, &verrs) {
+				return err
+			}
+			for _, e := range verrs {
+				fmt.Fprintf(out, "%s✗%s %s\n", red, reset, e)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d problem(s) found in %s", len(verrs), path)
+		}
+
+		fmt.Fprintf(out, "%s✓%s %s is valid (%s)\n",
+			green, reset, path, describeTargets(conf))
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Show prints the configuration after defaults and groups have been applied
+and inventory sources have been expanded into remote targets.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+
+		enc := yaml.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(conf)
+	},
+}
+
+// describeTargets summarizes what conf monitors, e.g. "local and 3 remote
+// target(s), 1 disabled".
+func describeTargets(conf *config.Config) string {
+	enabled := len(conf.FilterByTags(nil))
+	desc := fmt.Sprintf("%d remote target(s)", enabled)
+	if conf.Monitor.Local.Enabled {
+		desc = "local and " + desc
+	}
+	if disabled := len(conf.Monitor.Remote) - enabled; disabled > 0 {
+		desc += fmt.Sprintf(", %d disabled", disabled)
+	}
+	return desc
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deployTags      []string
+	deployBinaryDir string
+	deployRollback  bool
+	deployRemove    bool
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Install the gosysmesh agent on remote targets",
+	Long: `Deploy uploads the gosysmesh binary to each remote target's agent_path over
+SSH, for use with "mode: agent". The remote architecture is detected with
+uname -m, and the upload is checked against its SHA-256 and the version it
+reports before it replaces the current binary, which is kept for --rollback.
+
+Binaries for other architectures are taken from --binary-dir, named
+gosysmesh-linux-<arch> (e.g. gosysmesh-linux-arm64); the running executable is
+used for hosts that match it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if deployRollback && deployRemove {
+			return errors.New("--rollback and --remove cannot be used together")
+		}
+		conf, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		closeAudit, err := openAuditLog(conf)
+		if err != nil {
+			return err
+		}
+		defer closeAudit()
+
+		targets := conf.FilterByTags(deployTags)
+		if len(targets) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No remote targets to deploy to.")
+			return nil
+		}
+
+		opts := remote.DeployOptions{Action: remote.DeployInstall, Version: Version, BinaryDir: deployBinaryDir}
+		switch {
+		case deployRollback:
+			opts.Action = remote.DeployRollback
+		case deployRemove:
+			opts.Action = remote.DeployRemove
+		}
+
+		results := make([]remote.DeployResult, 0, len(targets))
+		for _, target := range targets {
+			results = append(results, remote.Deploy(target, opts))
+		}
+		if failed := printDeployResults(cmd.OutOrStdout(), opts.Action, results); failed > 0 {
+			return fmt.Errorf("%s failed on %d of %d target(s)", opts.Action, failed, len(results))
+		}
+		return nil
+	},
+}
+
+// printDeployResults renders one line per target and returns the number of failures.
+func printDeployResults(out io.Writer, action remote.DeployAction, results []remote.DeployResult) int {
+	failed := 0
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\t%sFAIL%s\t%s\t%v\n", r.Host, red, reset, r.Path, r.Err)
+			continue
+		}
+
+		detail := ""
+		switch action {
+		case remote.DeployInstall:
+			detail = fmt.Sprintf("%s  version %s  sha256 %.12s", r.Arch, r.Version, r.Checksum)
+		case remote.DeployRollback:
+			detail = "restored version " + r.Version
+		case remote.DeployRemove:
+			detail = "removed"
+		}
+		fmt.Fprintf(tw, "%s\t%sOK%s\t%s\t%s\n", r.Host, green, reset, r.Path, detail)
+	}
+	tw.Flush()
+	return failed
+}
+
+func init() {
+	deployCmd.Flags().StringSliceVarP(&deployTags, "tag", "t", nil, "Only deploy to remote targets with any of these tags or groups (repeatable)")
+	deployCmd.Flags().StringVar(&deployBinaryDir, "binary-dir", "", "Directory of prebuilt gosysmesh-linux-<arch> binaries")
+	deployCmd.Flags().BoolVar(&deployRollback, "rollback", false, "Restore the binary replaced by the last deploy")
+	deployCmd.Flags().BoolVar(&deployRemove, "remove", false, "Remove the agent binary and its backup")
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var doctorTags []string
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose SSH connectivity to remote targets",
+	Long: `Doctor checks each remote target step by step: DNS resolution, TCP
+reachability (through the jump hosts if any), the host key in known_hosts, SSH
+key permissions, authentication, and the remote commands gosysmesh needs.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		conf, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		closeAudit, err := openAuditLog(conf)
+		if err != nil {
+			return err
+		}
+		defer closeAudit()
+
+		targets := conf.FilterByTags(doctorTags)
+		if len(targets) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No remote targets to check.")
+			return nil
+		}
+
+		failed := 0
+		for _, target := range targets {
+			results := remote.Diagnose(target)
+			if printDiagnosis(cmd.OutOrStdout(), target, results) {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d target(s) failed checks", failed, len(targets))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%sAll %d target(s) passed.%s\n", green, len(targets), reset)
+		return nil
+	},
+}
+
+// sshAddress formats user@host:port, bracketing IPv6 addresses.
+func sshAddress(user, host string, port int) string {
+	return user + "@" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// printDiagnosis renders the results for one target as a table and reports
+// whether any step failed.
+func printDiagnosis(out io.Writer, target config.RemoteTarget, results []remote.CheckResult) bool {
+	route := sshAddress(target.User, target.Host, target.Port)
+	if chain, err := target.JumpChain(); err == nil && len(chain) > 0 {
+		hops := make([]string, len(chain))
+		for i, hop := range chain {
+			hops[i] = sshAddress(hop.User, hop.Host, hop.Port)
+		}
+		route += " via " + strings.Join(hops, " → ")
+	}
+	fmt.Fprintf(out, "%s%s%s%s (%s)\n", bold, cyan, target.Host, reset, route)
+
+	failed := false
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, r := range results {
+		color := green
+		switch r.Status {
+		case remote.CheckFail:
+			color = red
+			failed = true
+		case remote.CheckSkip:
+			color = yellow
+		}
+		fmt.Fprintf(tw, "  %s\t%s%s%s\t%s\n", r.Step, color, r.Status, reset, r.Detail)
+	}
+	tw.Flush()
+	fmt.Fprintln(out)
+	return failed
+}
+
+func init() {
+	doctorCmd.Flags().StringSliceVarP(&doctorTags, "tag", "t", nil, "Only check remote targets with any of these tags or groups (repeatable)")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/knownhosts"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostkeysTags []string
+	forgetPort   int
+)
+
+var hostkeysCmd = &cobra.Command{
+	Use:   "hostkeys",
+	Short: "List, pin, verify and forget the host keys of remote targets",
+	Long: `Hostkeys manages the SSH host keys gosysmesh trusts. Targets with
+host_key_policy strict (the default) use ~/.ssh/known_hosts; targets with tofu
+or pinned use the gosysmesh-managed ~/.gosysmesh/known_hosts.`,
+}
+
+var hostkeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the recorded host keys of each target",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		targets, err := hostkeysTargets()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		for _, target := range targets {
+			check := remote.ListHostKeys(target)
+			if check.Err != nil {
+				fmt.Fprintf(tw, "%s\t%s\t%serror%s\t%v\n", check.Name, check.Policy, red, reset, check.Err)
+				continue
+			}
+			if len(check.Recorded) == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%sunknown%s\tnot in %s\n", check.Name, check.Policy, yellow, reset, check.File)
+				continue
+			}
+			for _, k := range check.Recorded {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s%s\n", check.Name, check.Policy, k.Type, k.Fingerprint(), pinMark(target, k))
+			}
+		}
+		return tw.Flush()
+	},
+}
+
+var hostkeysPinCmd = &cobra.Command{
+	Use:   "pin",
+	Short: "Record the keys targets present now and print them for pinning",
+	Long: `Pin fetches the keys each target presents with ssh-keyscan, records them in
+the managed known_hosts, replacing any recorded before, and prints the
+host_key_policy and host_key_fingerprints settings that pin them. Only run it
+when you trust the network path to the hosts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		targets, err := hostkeysTargets()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		failed := 0
+		for _, target := range targets {
+			name := knownhosts.Name(target.Host, target.Port)
+			keys, err := remote.PinHostKeys(target)
+			if err != nil {
+				failed++
+				fmt.Fprintf(out, "# %s: %sFAIL%s %v\n", name, red, reset, err)
+				continue
+			}
+			fmt.Fprintf(out, "# %s\nhost_key_policy: %q\nhost_key_fingerprints:\n", name, config.HostKeyPinned)
+			for _, k := range keys {
+				fmt.Fprintf(out, "  - %q  # %s\n", k.Fingerprint(), k.Type)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d target(s) could not be pinned", failed, len(targets))
+		}
+		return nil
+	},
+}
+
+var hostkeysVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that targets still present their recorded or pinned keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		targets, err := hostkeysTargets()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		changed, failed := 0, 0
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		type alert struct {
+			target config.RemoteTarget
+			check  remote.HostKeyCheck
+		}
+		var alerts []alert
+		for _, target := range targets {
+			check := remote.VerifyHostKeys(target)
+			color, detail := green, check.File
+			switch check.Status {
+			case remote.HostKeyUnknown:
+				color, detail = yellow, "not in "+check.File
+			case remote.HostKeyChanged:
+				changed++
+				color = red
+				alerts = append(alerts, alert{target, check})
+			case remote.HostKeyUnreachable:
+				failed++
+				color, detail = yellow, check.Err.Error()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s%s%s\t%s\n", check.Name, check.Policy, color, check.Status, reset, detail)
+		}
+		tw.Flush()
+
+		for _, a := range alerts {
+			printHostKeyAlert(out, a.target, describeKeyChange(a.check))
+		}
+		switch {
+		case changed > 0:
+			return fmt.Errorf("%d of %d target(s) presented a changed host key", changed, len(targets))
+		case failed > 0:
+			return fmt.Errorf("%d of %d target(s) could not be checked", failed, len(targets))
+		}
+		return nil
+	},
+}
+
+var hostkeysForgetCmd = &cobra.Command{
+	Use:   "forget HOST...",
+	Short: "Remove hosts from the managed known_hosts",
+	Long: `Forget removes the recorded keys of each HOST from the managed known_hosts,
+for example after a host was rebuilt. Targets using the tofu policy trust the
+next key they see; pinned targets record the next key matching their pins.
+Check the new key with "hostkeys verify" before collecting from the host again.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+		for _, host := range args {
+			n, err := remote.ForgetHostKeys(host, forgetPort)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				fmt.Fprintf(out, "%s: no recorded keys\n", knownhosts.Name(host, forgetPort))
+				continue
+			}
+			fmt.Fprintf(out, "%s: removed %d key(s)\n", knownhosts.Name(host, forgetPort), n)
+		}
+		return nil
+	},
+}
+
+// hostkeysTargets returns the configured targets selected by --tag.
+func hostkeysTargets() ([]config.RemoteTarget, error) {
+	conf, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	targets := conf.FilterByTags(hostkeysTags)
+	if len(targets) == 0 {
+		return nil, errors.New("no remote targets selected")
+	}
+	return targets, nil
+}
+
+// pinMark flags, for pinned targets, whether k is one of the pinned keys.
+func pinMark(target config.RemoteTarget, k knownhosts.Key) string {
+	if target.KeyPolicy() != config.HostKeyPinned {
+		return ""
+	}
+	for _, fp := range target.HostKeyFingerprints {
+		if fp == k.Fingerprint() {
+			return "  (pinned)"
+		}
+	}
+	return "  " + red + "(not pinned)" + reset
+}
+
+// describeKeyChange lists the keys a host presents next to those recorded.
+func describeKeyChange(check remote.HostKeyCheck) string {
+	var lines []string
+	for _, k := range check.Recorded {
+		lines = append(lines, fmt.Sprintf("  recorded:  %s %s", k.Type, k.Fingerprint()))
+	}
+	for _, k := range check.Presented {
+		lines = append(lines, fmt.Sprintf("  presented: %s %s", k.Type, k.Fingerprint()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printHostKeyAlert prints the banner shown whenever target presents a key
+// other than the one recorded or pinned for it.
+func printHostKeyAlert(out io.Writer, target config.RemoteTarget, detail string) {
+	line := strings.Repeat("!", 72)
+	fmt.Fprintf(out, "%s%s%s\n", bold, red, line)
+	fmt.Fprintf(out, "ALERT: HOST KEY CHANGED FOR %s\n", knownhosts.Name(target.Host, target.Port))
+	fmt.Fprintln(out, "Someone could be intercepting the connection, or the host was rebuilt.")
+	fmt.Fprintln(out, "gosysmesh will not connect to it until the key is verified out of band.")
+	if detail != "" {
+		fmt.Fprintln(out, detail)
+	}
+	var fix string
+	switch target.KeyPolicy() {
+	case config.HostKeyStrict:
+		fix = "ssh-keygen -R '" + knownhosts.Name(target.Host, target.Port) + "'"
+	case config.HostKeyPinned:
+		fix = "pin the new fingerprint in host_key_fingerprints"
+	default:
+		fix = "gosysmesh hostkeys forget " + target.Host
+		if target.Port != 22 {
+			fix += fmt.Sprintf(" --port %d", target.Port)
+		}
+	}
+	fmt.Fprintf(out, "If the change is expected: %s\n", fix)
+	fmt.Fprintf(out, "%s%s\n", line, reset)
+}
+
+func init() {
+	hostkeysCmd.PersistentFlags().StringSliceVarP(&hostkeysTags, "tag", "t", nil, "Only use remote targets with any of these tags or groups (repeatable)")
+	hostkeysForgetCmd.Flags().IntVarP(&forgetPort, "port", "p", 22, "SSH port of the hosts")
+	hostkeysCmd.AddCommand(hostkeysListCmd)
+	hostkeysCmd.AddCommand(hostkeysPinCmd)
+	hostkeysCmd.AddCommand(hostkeysVerifyCmd)
+	hostkeysCmd.AddCommand(hostkeysForgetCmd)
+}
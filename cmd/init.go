@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var initOpts struct {
+	output         string
+	interval       string
+	hosts          []string
+	sshKey         string
+	proxyJump      string
+	keywords       []string
+	nonInteractive bool
+	skipSSHTest    bool
+	recordHostKeys bool
+	force          bool
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a configuration file interactively",
+	Long: `Init builds a configuration file by asking for the monitoring interval,
+local process filters and remote hosts. Each host is checked over SSH with the
+same transport start uses, and unknown host keys can be recorded on the spot.
+Process keywords are suggested from the processes running locally.
+
+With --non-interactive, every answer comes from flags and defaults.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runConfigInit(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+// prompter asks questions on an input stream, falling back to defaults when
+// running non-interactively or when the answer is empty.
+type prompter struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+}
+
+func (p *prompter) ask(question, def string) string {
+	if !p.interactive {
+		return def
+	}
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, _ := p.in.ReadString('\n')
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+	return def
+}
+
+func (p *prompter) confirm(question string, def bool) bool {
+	if !p.interactive {
+		return def
+	}
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	answer := strings.ToLower(p.ask(fmt.Sprintf("%s [%s]", question, choices), ""))
+	switch answer {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
+
+func runConfigInit(in io.Reader, out io.Writer) error {
+	p := &prompter{in: bufio.NewReader(in), out: out, interactive: !initOpts.nonInteractive}
+
+	path := initOpts.output
+	if path == "" {
+		path = cfgFile
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, ".gosysmesh.yaml")
+	}
+	if _, err := os.Stat(path); err == nil && !initOpts.force {
+		if !p.interactive || !p.confirm(fmt.Sprintf("%s already exists. Overwrite?", path), false) {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+	}
+
+	conf := config.Config{Interval: p.ask("Monitoring interval", initOpts.interval)}
+
+	// Local monitoring, with keywords suggested from running processes
+	keywords := initOpts.keywords
+	if len(keywords) == 0 {
+		suggested, err := collector.SuggestProcessKeywords(5)
+		if err == nil && len(suggested) > 0 {
+			fmt.Fprintf(out, "Busiest local processes: %s\n", strings.Join(suggested, ", "))
+			keywords = suggested
+		}
+	}
+	keywords = splitList(p.ask("Local process keywords (comma separated)", strings.Join(keywords, ",")))
+	conf.Monitor.Local = config.LocalMonitorConfig{
+		Enabled:        true,
+		ProcessFilters: config.ProcessFilterConfig{Keywords: keywords},
+	}
+
+	// Remote hosts from flags, then interactively
+	specs := append([]string{}, initOpts.hosts...)
+	for p.interactive {
+		spec := p.ask("Remote host as user@host[:port] (empty to finish)", "")
+		if spec == "" {
+			break
+		}
+		specs = append(specs, spec)
+	}
+
+	for _, spec := range specs {
+		target, err := initTarget(p, spec, keywords)
+		if err != nil {
+			fmt.Fprintf(out, "%s✗%s %s: %v\n", red, reset, spec, err)
+			continue
+		}
+		if !initOpts.skipSSHTest && !checkInitTarget(p, out, target) {
+			if !p.confirm(fmt.Sprintf("Keep %s in the config anyway?", target.Host), true) {
+				continue
+			}
+		}
+		conf.Monitor.Remote = append(conf.Monitor.Remote, target)
+	}
+
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	header := "# gosysmesh configuration generated by `gosysmesh config init`\n"
+	if err := os.WriteFile(path, append([]byte(header), data...), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	if _, err := config.LoadConfig(path); err != nil {
+		return fmt.Errorf("wrote %s but it does not validate: %w", path, err)
+	}
+	fmt.Fprintf(out, "%s✓%s Wrote %s with %d remote target(s)\n", green, reset, path, len(conf.Monitor.Remote))
+	return nil
+}
+
+// initTarget builds a remote target from a user@host[:port] spec, asking for
+// the settings that flags did not provide.
+func initTarget(p *prompter, spec string, keywords []string) (config.RemoteTarget, error) {
+	hops, err := config.ParseProxyJump(spec)
+	if err != nil || len(hops) != 1 {
+		return config.RemoteTarget{}, fmt.Errorf("expected user@host[:port]")
+	}
+	host := hops[0]
+	if host.User == "" {
+		host.User = p.ask(fmt.Sprintf("User for %s", host.Host), os.Getenv("USER"))
+	}
+	if host.Port == 0 {
+		host.Port = 22
+	}
+
+	sshKey := initOpts.sshKey
+	if sshKey == "" {
+		sshKey = defaultSSHKey()
+	}
+
+	return config.RemoteTarget{
+		Host:      host.Host,
+		User:      host.User,
+		Port:      host.Port,
+		SSHKey:    p.ask(fmt.Sprintf("SSH key for %s", host.Host), sshKey),
+		ProxyJump: p.ask(fmt.Sprintf("Jump host for %s (optional)", host.Host), initOpts.proxyJump),
+		ProcessFilters: config.ProcessFilterConfig{
+			Keywords: splitList(p.ask(fmt.Sprintf("Process keywords for %s", host.Host), strings.Join(keywords, ","))),
+		},
+	}, nil
+}
+
+// checkInitTarget tests SSH access to target, offering to record its host
+// key if it is not yet known. It reports whether the host is reachable.
+func checkInitTarget(p *prompter, out io.Writer, target config.RemoteTarget) bool {
+	fmt.Fprintf(out, "Testing SSH connection to %s... ", sshAddress(target.User, target.Host, target.Port))
+	info, err := remote.Probe(target)
+	if err == nil {
+		fmt.Fprintf(out, "%sok%s (%s)\n", green, reset, info)
+		return true
+	}
+	fmt.Fprintf(out, "%sfailed%s\n", red, reset)
+
+	if remote.IsHostKeyError(err) && target.ProxyJump == "" {
+		knownHosts, khErr := remote.DefaultKnownHostsPath()
+		if khErr == nil && p.confirm(fmt.Sprintf("Host key for %s is unknown. Record it in %s?", target.Host, knownHosts), initOpts.recordHostKeys) {
+			if err := remote.RecordHostKey(target.Host, target.Port, knownHosts); err != nil {
+				fmt.Fprintf(out, "  could not record host key: %v\n", err)
+				return false
+			}
+			fmt.Fprintf(out, "  recorded host key, retrying... ")
+			if info, err = remote.Probe(target); err == nil {
+				fmt.Fprintf(out, "%sok%s (%s)\n", green, reset, info)
+				return true
+			}
+			fmt.Fprintf(out, "%sfailed%s\n", red, reset)
+		}
+	}
+	fmt.Fprintf(out, "  %v\n", strings.TrimSpace(err.Error()))
+	return false
+}
+
+// defaultSSHKey returns the first private key found in ~/.ssh, written with a
+// leading ~ so the generated config stays portable.
+func defaultSSHKey() string {
+	home, err := os.UserHomeDir()
+	if err == nil {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			if _, err := os.Stat(filepath.Join(home, ".ssh", name)); err == nil {
+				return "~/.ssh/" + name
+			}
+		}
+	}
+	return "~/.ssh/id_ed25519"
+}
+
+// splitList splits a comma separated answer, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func init() {
+	configInitCmd.Flags().StringVarP(&initOpts.output, "output", "o", "", "file to write (default: --config or $HOME/.gosysmesh.yaml)")
+	configInitCmd.Flags().StringVar(&initOpts.interval, "interval", "30s", "monitoring interval")
+	configInitCmd.Flags().StringSliceVar(&initOpts.hosts, "host", nil, "remote host as user@host[:port] (repeatable)")
+	configInitCmd.Flags().StringVar(&initOpts.sshKey, "ssh-key", "", "SSH key for remote hosts (default: first key found in ~/.ssh)")
+	configInitCmd.Flags().StringVar(&initOpts.proxyJump, "proxy-jump", "", "jump host for remote hosts")
+	configInitCmd.Flags().StringSliceVar(&initOpts.keywords, "keywords", nil, "process keywords (default: suggested from running processes)")
+	configInitCmd.Flags().BoolVar(&initOpts.nonInteractive, "non-interactive", false, "do not prompt; use flags and defaults")
+	configInitCmd.Flags().BoolVar(&initOpts.skipSSHTest, "skip-ssh-test", false, "do not test SSH access to remote hosts")
+	configInitCmd.Flags().BoolVar(&initOpts.recordHostKeys, "record-host-keys", false, "record unknown host keys without asking")
+	configInitCmd.Flags().BoolVar(&initOpts.force, "force", false, "overwrite an existing file")
+	configCmd.AddCommand(configInitCmd)
+}
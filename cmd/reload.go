@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// configHolder keeps the active configuration for loop mode and swaps it
+// atomically when a reload succeeds.
+type configHolder struct {
+	path    string
+	current atomic.Pointer[config.Config]
+}
+
+func newConfigHolder(path string, conf *config.Config) *configHolder {
+	h := &configHolder{path: path}
+	h.current.Store(conf)
+	return h
+}
+
+// Load returns the active configuration.
+func (h *configHolder) Load() *config.Config {
+	return h.current.Load()
+}
+
+// Reload re-reads and validates the config file. On failure the previous
+// configuration stays active and the error is returned.
+func (h *configHolder) Reload() (*config.Config, error) {
+	conf, err := config.LoadConfig(h.path)
+	if err != nil {
+		return nil, err
+	}
+	h.current.Store(conf)
+	return conf, nil
+}
+
+// reloadConfig performs a reload requested by a file change or SIGHUP and
+// reports the outcome.
+func reloadConfig(holder *configHolder, reason string) (*config.Config, bool) {
+	conf, err := holder.Reload()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%sConfig reload failed (%s), keeping previous config:%s %v\n", bold, red, reason, reset, err)
+		return nil, false
+	}
+	fmt.Printf("Config reloaded (%s): %s, interval %s\n", reason, describeTargets(conf), conf.Interval)
+	return conf, true
+}
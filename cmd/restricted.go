@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/agent"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var restrictedLogFile string
+
+var restrictedShellCmd = &cobra.Command{
+	Use:   "restricted-shell",
+	Short: "Forced command that only runs gosysmesh collection commands",
+	Long: `Restricted-shell is meant to be the forced command of the monitoring key in
+authorized_keys on each remote host:
+
+  command="/usr/local/bin/gosysmesh restricted-shell",no-pty,no-port-forwarding,no-agent-forwarding,no-X11-forwarding ssh-ed25519 AAAA...
+
+It reads the command requested by the client from SSH_ORIGINAL_COMMAND and runs
+it only if it is exactly one of the read-only commands in gosysmesh's remote
+command registry. The agent command is served by restricted-shell itself. Every attempt is logged to
+syslog (auth facility), or to --log-file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		logger, closeLog, err := restrictedLogger(restrictedLogFile)
+		if err != nil {
+			return err
+		}
+
+		code := runRestricted(os.Getenv("SSH_ORIGINAL_COMMAND"), cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr(), logger)
+		closeLog()
+		if code != 0 {
+			// The client reads the remote command's exit status, as from a shell
+			os.Exit(code)
+		}
+		return nil
+	},
+}
+
+// runRestricted runs original if it is an allowed collection command and
+// returns the exit status to report to the client.
+func runRestricted(original string, stdin io.Reader, stdout, stderr io.Writer, logger *log.Logger) int {
+	client := os.Getenv("SSH_CLIENT")
+	command, _, ok := remote.MatchCommand(original)
+	if !ok {
+		logger.Printf("rejected command from %q: %s", client, strconv.Quote(truncate(original, 512)))
+		fmt.Fprintln(stderr, "gosysmesh restricted-shell: command not allowed")
+		return 1
+	}
+	if !command.ReadOnly {
+		logger.Printf("rejected %s command from %q: not read-only", command.ID, client)
+		fmt.Fprintln(stderr, "gosysmesh restricted-shell: command not allowed")
+		return 1
+	}
+	logger.Printf("allowed %s command from %q", command.ID, client)
+
+	if command.ID == remote.CommandAgent {
+		if err := agent.Serve(stdin, stdout, agent.LocalCollectors()); err != nil {
+			logger.Printf("agent failed: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	// The command is exactly what the registry builds for its arguments, so
+	// passing it to the shell runs nothing but the known pipeline
+	sh := exec.Command("/bin/sh", "-c", original)
+	sh.Stdin, sh.Stdout, sh.Stderr = stdin, stdout, stderr
+	if err := sh.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		logger.Printf("failed to run %s command: %v", command.ID, err)
+		return 1
+	}
+	return 0
+}
+
+// restrictedLogger returns the logger recording restricted-shell attempts:
+// path if set, otherwise syslog, falling back to stderr when syslog is unavailable.
+func restrictedLogger(path string) (*log.Logger, func(), error) {
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		return log.New(f, "gosysmesh restricted-shell: ", log.LstdFlags), func() { f.Close() }, nil
+	}
+
+	w, err := openSyslog()
+	if err != nil {
+		return log.New(os.Stderr, "gosysmesh restricted-shell: ", log.LstdFlags), func() {}, nil
+	}
+	return log.New(w, "restricted-shell: ", 0), func() { w.Close() }, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func init() {
+	restrictedShellCmd.Flags().StringVar(&restrictedLogFile, "log-file", "", "Append attempts to this file instead of syslog")
+}
@@ -7,11 +7,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/remote"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+	setArgs []string
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -28,6 +33,7 @@ It provides real-time CPU, memory, and disk usage statistics along with filtered
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	remote.StopKeyAgent()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -36,7 +42,16 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gosysmesh.yaml)")
+	rootCmd.PersistentFlags().StringArrayVar(&setArgs, "set", nil, "override a config key, e.g. --set monitor.remote[0].ssh_key=~/.ssh/ci (repeatable)")
 	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(hostkeysCmd)
+	rootCmd.AddCommand(restrictedShellCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -55,7 +70,9 @@ func initConfig() {
 		viper.SetConfigName(".gosysmesh")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// GOSYSMESH_* environment variables and --set are applied by
+	// config.LoadConfig on top of the file, so that reloads keep them
+	cobra.CheckErr(config.SetFlagOverrides(setArgs))
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
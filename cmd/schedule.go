@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/schedule"
+)
+
+// localJobPrefix starts the keys of the jobs collecting from the local host.
+const localJobPrefix = "local/"
+
+// remoteJobPrefix starts the keys of the jobs collecting from target. Targets
+// are told apart by address, so a reload that keeps a target keeps its jobs'
+// schedule.
+func remoteJobPrefix(target config.RemoteTarget) string {
+	return "remote/" + sshAddress(target.User, target.Host, target.Port) + "/"
+}
+
+// scheduleJobs returns a job per metric family of the local host, if it is
+// monitored, and of each remote target selected by --tag.
+func scheduleJobs(conf *config.Config) []schedule.Job {
+	var jobs []schedule.Job
+	add := func(prefix string, intervalFor func(config.MetricFamily) time.Duration) {
+		for _, family := range config.MetricFamilies {
+			interval := intervalFor(family)
+			jobs = append(jobs, schedule.Job{
+				Key: prefix + string(family),
+				// Families of a host sharing an interval are collected
+				// together, in one round-trip
+				Group:    prefix,
+				Interval: interval,
+				Jitter:   conf.JitterFor(interval),
+			})
+		}
+	}
+
+	if conf.Monitor.Local.Enabled {
+		add(localJobPrefix, conf.LocalIntervalFor)
+	}
+	for _, target := range conf.FilterByTags(tagFilter) {
+		add(remoteJobPrefix(target), func(family config.MetricFamily) time.Duration {
+			return conf.IntervalFor(target, family)
+		})
+	}
+	return jobs
+}
+
+// dueKeys returns the keys of jobs as a set.
+func dueKeys(jobs []schedule.Job) map[string]bool {
+	due := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		due[job.Key] = true
+	}
+	return due
+}
+
+// dueFamilies returns the families whose job under prefix is due, or every
+// family if due is nil.
+func dueFamilies(prefix string, due map[string]bool) []config.MetricFamily {
+	var families []config.MetricFamily
+	for _, family := range config.MetricFamilies {
+		if due == nil || due[prefix+string(family)] {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// describeIntervals summarises the intervals of jobs, e.g. "every 30s" or
+// "every 5s to 5m0s".
+func describeIntervals(jobs []schedule.Job) string {
+	if len(jobs) == 0 {
+		return "nothing selected"
+	}
+	intervals := make([]time.Duration, len(jobs))
+	for i, job := range jobs {
+		intervals[i] = job.Interval
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+
+	lowest, highest := intervals[0], intervals[len(intervals)-1]
+	if lowest == highest {
+		return fmt.Sprintf("every %s", lowest)
+	}
+	return fmt.Sprintf("every %s to %s", lowest, highest)
+}
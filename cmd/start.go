@@ -1,16 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ChristianThibeault/gosysmesh/internal/collector"
 	"github.com/ChristianThibeault/gosysmesh/internal/config"
 	"github.com/ChristianThibeault/gosysmesh/internal/remote"
+	"github.com/ChristianThibeault/gosysmesh/internal/schedule"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 const (
@@ -51,59 +55,197 @@ func printHostProcesses(title string, timestamp time.Time, procs []collector.Mon
 
 		fmt.Printf("%s PID %-6d: %s\n", conn, p.PID, p.Cmdline)
 		fmt.Printf("│   ├── %sCPU:%s %.1f%%   %sMEM:%s %.1f%%\n", cpuColor, reset, p.CPU, memColor, reset, p.MEM)
-		fmt.Printf("│   └── Start: %s   Stat: %s   User: %s%s%s\n",
-			p.StartTime, p.Status, blue, p.User, reset)
+		fmt.Printf("│   └── Up: %s   Stat: %s   User: %s%s%s\n",
+			formatAge(p.Age), p.Status, blue, p.User, reset)
 	}
 	fmt.Println()
 }
 
+// remoteErrorHints tell the operator where to look for each class of failure.
+var remoteErrorHints = map[remote.ErrorClass]string{
+	remote.ClassConnection: "host unreachable",
+	remote.ClassAuth:       "authentication failed; check ssh_key and authorized_keys",
+	remote.ClassHostKey:    "host key unknown or changed; check known_hosts",
+	remote.ClassTimeout:    "timed out",
+	remote.ClassCommand:    "remote command failed",
+	remote.ClassParse:      "unexpected output from remote command",
+}
 
-var (
-	loopMode bool
-)
+// printRemoteError renders a remote collection failure with its class, so
+// that e.g. a host key problem stands out from a host being down.
+func printRemoteError(host string, err error) {
+	var re *remote.Error
+	if !errors.As(err, &re) {
+		fmt.Fprintf(os.Stderr, "Remote %s error: %v\n", host, err)
+		return
+	}
 
-// runMonitoring performs a single monitoring cycle
-func runMonitoring(conf *config.Config) {
-	// Collect and print local system stats
-	stats, err := collector.GetSystemStats()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error collecting stats: %v\n", err)
-	} else {
-		fmt.Printf("[%s] CPU: %.1f%% | MEM: %.2f/%.2f GB | DISK: %.1f/%.1f GB\n",
-			stats.Timestamp.Format("15:04:05"),
-			stats.CPUPercent,
-			stats.MemUsedGB, stats.MemTotalGB,
-			stats.DiskUsedGB, stats.DiskTotalGB,
-		)
+	fmt.Fprintf(os.Stderr, "[%s][%s] %s%s%s%s: %s — %s\n",
+		time.Now().Format("15:04:05"), host,
+		bold, errorClassColor(re.Class), strings.ToUpper(re.Class.String()), reset,
+		remoteErrorHints[re.Class], re.Reason())
+}
+
+// remoteReason returns the most useful line describing err.
+func remoteReason(err error) string {
+	var re *remote.Error
+	if errors.As(err, &re) {
+		return re.Reason()
 	}
+	return err.Error()
+}
 
-	// Collect and print local processes
-	filtered, err := collector.GetFilteredProcesses(conf.Monitor.Local.ProcessFilters)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error filtering processes: %v\n", err)
-	} else {
-		if len(filtered) > 0 {
-		printHostProcesses("local", time.Now(), filtered)
+// printDegraded reports a section of a host's metrics that could not be
+// collected while the rest of the host's data is still shown.
+func printDegraded(timestamp time.Time, host, section string, err error) {
+	detail := err.Error()
+	color := yellow
+	var re *remote.Error
+	if errors.As(err, &re) {
+		detail = fmt.Sprintf("%s: %s — %s", re.Class, remoteErrorHints[re.Class], re.Reason())
+		color = errorClassColor(re.Class)
+	}
+	fmt.Printf("[%s][%s] %s%s DEGRADED%s (%s)\n",
+		timestamp.Format("15:04:05"), host, color, section, reset, detail)
+}
+
+// agentFallbackNoted holds the hosts whose fallback from agent mode to
+// scraping has already been reported, so that loop mode reports it only once.
+var agentFallbackNoted = map[string]bool{}
+
+// noteAgentFallback reports the first time a host in agent mode is scraped instead.
+func noteAgentFallback(metrics *remote.RemoteMetrics) {
+	if metrics.AgentFallback == nil {
+		if metrics.Agent {
+			delete(agentFallbackNoted, metrics.Host)
+		}
+		return
+	}
+	if agentFallbackNoted[metrics.Host] {
+		return
 	}
+	agentFallbackNoted[metrics.Host] = true
+	fmt.Fprintf(os.Stderr, "[%s] %sAGENT UNAVAILABLE%s, falling back to ps/top scraping: %v\n",
+		metrics.Host, yellow, reset, metrics.AgentFallback)
+}
 
+func errorClassColor(class remote.ErrorClass) string {
+	if class == remote.ClassHostKey || class == remote.ClassAuth {
+		return red
+	}
+	return yellow
+}
+
+// printErrorCounts summarises remote failures by class, if there were any.
+func printErrorCounts() {
+	var parts []string
+	for _, class := range remote.ErrorClasses() {
+		if n := remote.ErrorCounts()[class]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", class, n))
+		}
+	}
+	if len(parts) > 0 {
+		fmt.Printf("Remote errors: %s\n", strings.Join(parts, " "))
+	}
+}
+
+// formatSkew renders a signed clock skew with millisecond precision, e.g. "+1.25s".
+func formatSkew(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+// formatAge renders a process age as its two most significant units, e.g. "3d4h" or "12m5s".
+func formatAge(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	d = d.Truncate(time.Second)
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+
+var (
+	loopMode  bool
+	tagFilter []string
+)
+
+// collectRemote collects from remote targets; tests replace it.
+var collectRemote = remote.CollectRemoteFamilies
+
+// runMonitoring performs a single monitoring cycle. If due is not nil only
+// the scheduled jobs it holds are collected, otherwise every metric family
+// of every target.
+func runMonitoring(conf *config.Config, due map[string]bool) {
+	if conf.Monitor.Local.Enabled {
+		if families := dueFamilies(localJobPrefix, due); len(families) > 0 {
+			runLocalMonitoring(conf, families)
+		}
 	}
 
 	// Collect and print remote stats
-	for _, target := range conf.Monitor.Remote {
-		metrics, err := remote.CollectRemoteStats(target)
+	for _, target := range conf.FilterByTags(tagFilter) {
+		families := dueFamilies(remoteJobPrefix(target), due)
+		if len(families) == 0 {
+			continue
+		}
+
+		metrics, err := collectRemote(target, families)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Remote %s error: %v\n", target.Host, err)
+			printRemoteError(target.Host, err)
+			if remote.IsHostKeyChanged(err) {
+				printHostKeyAlert(os.Stderr, target, "  "+remoteReason(err))
+			}
 			continue
 		}
 
+		noteAgentFallback(metrics)
+
 		// Print remote system stats
+		if metrics.SystemErr != nil {
+			printDegraded(metrics.Timestamp, metrics.Host, "SYSTEM STATS", metrics.SystemErr)
+		}
+		if metrics.DiskErr != nil && metrics.DiskErr != metrics.SystemErr {
+			printDegraded(metrics.Timestamp, metrics.Host, "DISK", metrics.DiskErr)
+		}
 		if metrics.SystemStats != nil {
-			fmt.Printf("[%s][%s] CPU: %.1f%% | MEM: %.2f/%.2f GB | DISK: %.1f/%.1f GB\n",
-				metrics.Timestamp.Format("15:04:05"), metrics.Host,
-				metrics.SystemStats.CPUPercent,
-				metrics.SystemStats.MemUsedGB, metrics.SystemStats.MemTotalGB,
-				metrics.SystemStats.DiskUsedGB, metrics.SystemStats.DiskTotalGB,
-			)
+			parts := statsParts(metrics.SystemStats, metrics.Has(config.FamilySystem) && metrics.SystemErr == nil, metrics.Has(config.FamilyDisk) && metrics.DiskErr == nil)
+			if metrics.Has(config.FamilySystem) && metrics.SystemErr == nil {
+				parts = append(parts, "SKEW: "+formatSkew(metrics.ClockSkew))
+			}
+			parts = append(parts, "RTT: "+metrics.RoundTrip.Round(time.Millisecond).String())
+			fmt.Printf("[%s][%s] %s\n", metrics.Timestamp.Format("15:04:05"), metrics.Host, strings.Join(parts, " | "))
+		}
+
+		if threshold := target.ClockSkewThreshold(); metrics.SkewExceeded(threshold) {
+			fmt.Fprintf(os.Stderr, "%s%sALERT%s [%s] clock skew %s exceeds max_clock_skew %s\n",
+				bold, red, reset, metrics.Host, formatSkew(metrics.ClockSkew), threshold)
+		}
+
+		if !metrics.Has(config.FamilyProcesses) {
+			continue
+		}
+		if metrics.ProcessErr != nil {
+			printDegraded(metrics.Timestamp, metrics.Host, "PROCESSES", metrics.ProcessErr)
+			continue
 		}
 
 		fmt.Printf("[%s][%s] %d processes matched\n",
@@ -115,6 +257,71 @@ func runMonitoring(conf *config.Config) {
 	}
 }
 
+// statsParts renders the CPU and memory usage if system is set, and the disk
+// usage if disk is set, as parts of a stats line.
+func statsParts(stats *collector.SystemStats, system, disk bool) []string {
+	var parts []string
+	if system {
+		parts = append(parts,
+			fmt.Sprintf("CPU: %.1f%%", stats.CPUPercent),
+			fmt.Sprintf("MEM: %.2f/%.2f GB", stats.MemUsedGB, stats.MemTotalGB))
+	}
+	if disk {
+		parts = append(parts, fmt.Sprintf("DISK: %.1f/%.1f GB", stats.DiskUsedGB, stats.DiskTotalGB))
+	}
+	return parts
+}
+
+// runLocalMonitoring collects and prints the local metric families
+func runLocalMonitoring(conf *config.Config, families []config.MetricFamily) {
+	system, disk, processes := false, false, false
+	for _, family := range families {
+		switch family {
+		case config.FamilySystem:
+			system = true
+		case config.FamilyDisk:
+			disk = true
+		case config.FamilyProcesses:
+			processes = true
+		}
+	}
+
+	// Collect and print local system stats
+	if system || disk {
+		stats, err := collector.GetSystemStats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error collecting stats: %v\n", err)
+		} else {
+			fmt.Printf("[%s] %s\n", stats.Timestamp.Format("15:04:05"), strings.Join(statsParts(stats, system, disk), " | "))
+		}
+	}
+
+	// Collect and print local processes
+	if !processes {
+		return
+	}
+	filtered, err := collector.GetFilteredProcesses(conf.Monitor.Local.ProcessFilters)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error filtering processes: %v\n", err)
+	} else {
+		if len(filtered) > 0 {
+		printHostProcesses("local", time.Now(), filtered)
+	}
+
+	}
+}
+
+// setRedactor masks the built-in and configured secret patterns in every
+// command line collected from now on.
+func setRedactor(conf *config.Config) error {
+	r, err := conf.Redact.Redactor()
+	if err != nil {
+		return err
+	}
+	collector.SetRedactor(r)
+	return nil
+}
+
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start system monitoring",
@@ -124,42 +331,114 @@ var startCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 			os.Exit(1)
 		}
+		closeAudit, err := openAuditLog(conf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open audit log: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { closeAudit() }()
+		if err := setRedactor(conf); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid redaction patterns: %v\n", err)
+			os.Exit(1)
+		}
 
 		if loopMode {
-			interval, err := time.ParseDuration(conf.Interval)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Invalid interval: %v\n", err)
-				os.Exit(1)
-			}
+			// Each metric family of each target is a job on its own
+			// schedule, replacing the single ticker shared by all targets
+			sched := schedule.New(nil)
+			jobs := scheduleJobs(conf)
+			sched.Set(jobs, time.Now())
 
-			fmt.Printf("Starting system monitor in loop mode: every %s\n", interval)
+			fmt.Printf("Starting system monitor in loop mode: %d collection jobs %s\n", len(jobs), describeIntervals(jobs))
 
-			ticker := time.NewTicker(interval)
-			defer ticker.Stop()
+			timer := time.NewTimer(0)
+			defer timer.Stop()
+			resetTimer := func() {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				if next, ok := sched.Next(); ok {
+					timer.Reset(time.Until(next))
+				}
+			}
+			resetTimer()
 
 			quit := make(chan os.Signal, 1)
 			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-			// Run initial monitoring
-			runMonitoring(conf)
+			// Reload the config on SIGHUP or when the file changes. Reloads are
+			// applied here, between monitoring cycles.
+			holder := newConfigHolder(cfgFile, conf)
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			changed := make(chan struct{}, 1)
+			if path := viper.ConfigFileUsed(); path != "" {
+				holder.path = path
+				watcher, err := config.Watch(path, func() {
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Config file watching disabled: %v\n", err)
+				} else {
+					defer watcher.Close()
+				}
+			}
+
+			reload := func(reason string) {
+				newConf, ok := reloadConfig(holder, reason)
+				if !ok {
+					return
+				}
+				// Jobs kept across the reload keep their next run
+				sched.Set(scheduleJobs(newConf), time.Now())
+				resetTimer()
+				if err := setRedactor(newConf); err != nil {
+					fmt.Fprintf(os.Stderr, "Invalid redaction patterns, keeping previous ones: %v\n", err)
+				}
+				if newConf.Audit != conf.Audit {
+					reopened, err := openAuditLog(newConf)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to reopen audit log, keeping %s: %v\n", conf.Audit.Path, err)
+						return
+					}
+					closeAudit()
+					closeAudit = reopened
+				}
+				conf = newConf
+			}
 
+			// The first run of each job falls within its jitter of now, so
+			// targets do not all connect at once on startup
 			for {
 				select {
-				case <-ticker.C:
-					runMonitoring(conf)
+				case <-timer.C:
+					runMonitoring(holder.Load(), dueKeys(sched.Due(time.Now())))
+					resetTimer()
+				case <-changed:
+					reload("file changed")
+				case <-hup:
+					reload("SIGHUP")
 				case <-quit:
+					printErrorCounts()
 					fmt.Println("Exiting system monitor.")
 					return
 				}
 			}
 		} else {
-			// Run once
-			runMonitoring(conf)
+			// Run once, collecting every metric family
+			runMonitoring(conf, nil)
 		}
 	},
 }
 
 func init() {
 	startCmd.Flags().BoolVarP(&loopMode, "loop", "l", false, "Run continuously (default: run once)")
+	startCmd.Flags().StringSliceVarP(&tagFilter, "tag", "t", nil, "Only monitor remote targets with any of these tags or groups (repeatable)")
 }
 
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package cmd
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog reports that syslog is not available on this platform.
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
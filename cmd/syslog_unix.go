@@ -0,0 +1,13 @@
+//go:build !windows && !plan9
+
+package cmd
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openSyslog connects to the local syslog daemon on the auth facility.
+func openSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_AUTH|syslog.LOG_NOTICE, "gosysmesh")
+}
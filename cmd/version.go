@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Version is the gosysmesh version, set at build time with
+// -ldflags "-X github.com/ChristianThibeault/gosysmesh/cmd.Version=v1.2.3".
+var Version = "dev"
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the gosysmesh version",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(cmd.OutOrStdout(), "gosysmesh %s\n", Version)
+	},
+}
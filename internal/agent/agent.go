@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// Collectors gathers the data served by the agent. The zero value is not
+// usable; LocalCollectors returns the real ones.
+type Collectors struct {
+	SystemStats func() (*collector.SystemStats, error)
+	Processes   func(filters config.ProcessFilterConfig) ([]collector.MonitoredProcess, error)
+}
+
+// LocalCollectors returns the collectors for the host the agent runs on.
+func LocalCollectors() Collectors {
+	return Collectors{
+		SystemStats: collector.GetSystemStats,
+		Processes:   collector.GetFilteredProcesses,
+	}
+}
+
+// Serve answers requests read from r on w until r is closed.
+func Serve(r io.Reader, w io.Writer, c Collectors) error {
+	in := bufio.NewReader(r)
+	for {
+		var req Request
+		if err := ReadFrame(in, &req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := WriteFrame(w, handle(req, c)); err != nil {
+			return err
+		}
+	}
+}
+
+// handle builds the response to a single request.
+func handle(req Request, c Collectors) Response {
+	switch req.Method {
+	case MethodHello:
+		return Response{Protocol: ProtocolVersion, Time: time.Now()}
+
+	case MethodCollect:
+		var resp Response
+		if stats, err := c.SystemStats(); err != nil {
+			resp.SystemError = err.Error()
+		} else {
+			resp.System = stats
+		}
+		if procs, err := c.Processes(req.Filters); err != nil {
+			resp.ProcessError = err.Error()
+		} else {
+			resp.Processes = procs
+		}
+		resp.Time = time.Now()
+		return resp
+
+	default:
+		return Response{Error: fmt.Sprintf("unknown method %q", req.Method), Time: time.Now()}
+	}
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("Last login: Mon Mar 10 12:00:00\n")
+	require.NoError(t, WriteFrame(&buf, Request{Method: MethodHello}))
+	require.NoError(t, WriteFrame(&buf, Request{Method: MethodCollect, Filters: config.ProcessFilterConfig{Keywords: []string{"nginx"}}}))
+
+	r := bufio.NewReader(&buf)
+	var req Request
+	require.NoError(t, ReadFrame(r, &req), "noise before the first frame is skipped")
+	assert.Equal(t, MethodHello, req.Method)
+	require.NoError(t, ReadFrame(r, &req))
+	assert.Equal(t, []string{"nginx"}, req.Filters.Keywords)
+	assert.ErrorIs(t, ReadFrame(r, &req), io.EOF)
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"bad length", framePrefix + "abc\n{}\n"},
+		{"negative length", framePrefix + "-1\n{}\n"},
+		{"truncated payload", framePrefix + "20\n{}\n"},
+		{"invalid json", framePrefix + "2\n{]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req Request
+			err := ReadFrame(bufio.NewReader(strings.NewReader(tt.input)), &req)
+			assert.Error(t, err)
+			assert.NotErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	var gotFilters config.ProcessFilterConfig
+	c := Collectors{
+		SystemStats: func() (*collector.SystemStats, error) {
+			return &collector.SystemStats{CPUPercent: 12.5}, nil
+		},
+		Processes: func(filters config.ProcessFilterConfig) ([]collector.MonitoredProcess, error) {
+			gotFilters = filters
+			return nil, errors.New("permission denied")
+		},
+	}
+
+	var in, out bytes.Buffer
+	require.NoError(t, WriteFrame(&in, Request{Method: MethodHello}))
+	require.NoError(t, WriteFrame(&in, Request{Method: MethodCollect, Filters: config.ProcessFilterConfig{Users: []string{"postgres"}}}))
+	require.NoError(t, WriteFrame(&in, Request{Method: "reboot"}))
+	require.NoError(t, Serve(&in, &out, c))
+
+	r := bufio.NewReader(&out)
+	var hello, collect, unknown Response
+	require.NoError(t, ReadFrame(r, &hello))
+	assert.Equal(t, ProtocolVersion, hello.Protocol)
+	assert.WithinDuration(t, time.Now(), hello.Time, time.Minute)
+
+	require.NoError(t, ReadFrame(r, &collect))
+	assert.Equal(t, 12.5, collect.System.CPUPercent)
+	assert.Equal(t, "permission denied", collect.ProcessError)
+	assert.Equal(t, []string{"postgres"}, gotFilters.Users)
+
+	require.NoError(t, ReadFrame(r, &unknown))
+	assert.Contains(t, unknown.Error, "unknown method")
+}
@@ -0,0 +1,101 @@
+// Package agent implements the gosysmesh agent: a process started on a
+// remote host over SSH that runs the local collectors and exchanges framed
+// JSON messages with the controller on stdin and stdout.
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// ProtocolVersion is bumped whenever a change to the messages would break
+// an older controller or agent.
+const ProtocolVersion = 1
+
+// maxFrameSize bounds a single message, guarding against reading garbage as a length.
+const maxFrameSize = 64 << 20
+
+// framePrefix starts every frame header. Lines before a header, such as
+// output from a remote shell profile, are skipped.
+const framePrefix = "@@gosysmesh:frame:"
+
+// Methods understood by the agent.
+const (
+	MethodHello   = "hello"
+	MethodCollect = "collect"
+)
+
+// Request is a message from the controller to the agent.
+type Request struct {
+	Method  string                     `json:"method"`
+	Filters config.ProcessFilterConfig `json:"filters,omitempty"`
+}
+
+// Response is the agent's answer to a single Request.
+type Response struct {
+	Protocol int    `json:"protocol,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	// Time is the agent host's clock when the response was built.
+	Time time.Time `json:"time"`
+
+	System       *collector.SystemStats       `json:"system,omitempty"`
+	SystemError  string                       `json:"system_error,omitempty"`
+	Processes    []collector.MonitoredProcess `json:"processes,omitempty"`
+	ProcessError string                       `json:"process_error,omitempty"`
+}
+
+// WriteFrame writes v as one frame: a header line carrying the payload
+// length, followed by the JSON payload and a newline.
+func WriteFrame(w io.Writer, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s%d\n%s\n", framePrefix, len(payload), payload); err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// ReadFrame reads the next frame from r into v. It returns io.EOF when r
+// ends before another frame header.
+func ReadFrame(r *bufio.Reader, v interface{}) error {
+	var size int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && strings.TrimSpace(line) == "" {
+				return io.EOF
+			}
+			return fmt.Errorf("failed to read frame header: %w", err)
+		}
+		header, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), framePrefix)
+		if !ok {
+			continue
+		}
+		size, err = strconv.Atoi(header)
+		if err != nil || size < 0 || size > maxFrameSize {
+			return fmt.Errorf("invalid frame length %q", header)
+		}
+		break
+	}
+
+	payload := make([]byte, size+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return fmt.Errorf("truncated frame: %w", err)
+	}
+	if err := json.Unmarshal(payload[:size], v); err != nil {
+		return fmt.Errorf("failed to decode frame: %w", err)
+	}
+	return nil
+}
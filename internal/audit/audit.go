@@ -0,0 +1,141 @@
+// Package audit records every command gosysmesh runs on remote hosts in an
+// append-only JSON lines file with size-based rotation.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Entry is one audited remote command execution.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	Port       int       `json:"port"`
+	User       string    `json:"user"`
+	JumpHosts  []string  `json:"jump_hosts,omitempty"` // user@host:port, first hop first
+	CommandID  string    `json:"command_id"`
+	Command    string    `json:"command"`
+	ExitStatus int       `json:"exit_status"` // -1 when the command did not run to completion
+	ErrorClass string    `json:"error_class,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	BytesSent  int64     `json:"bytes_sent,omitempty"` // stdin fed to the command
+	BytesOut   int64     `json:"bytes_returned"`
+}
+
+// Logger appends entries to a file, rotating it when it would grow past
+// maxSize. Rotated files are named path.1 (newest) to path.<maxBackups>.
+type Logger struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// Open opens, creating it and its directory if needed, the audit log at
+// path. A maxSize of zero disables rotation.
+func Open(path string, maxSize int64, maxBackups int) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	l := &Logger{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Path returns the path of the active log file.
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Log appends e as a single JSON line.
+func (l *Logger) Log(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit log %s is closed", l.path)
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts the existing backups up by one, drops the oldest, and
+// starts a new active file.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	l.file = nil
+
+	if l.maxBackups < 1 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+		return l.open()
+	}
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupName(l.path, i), backupName(l.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, backupName(l.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return l.open()
+}
+
+func backupName(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// Close closes the log file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(id string) Entry {
+	return Entry{
+		Time:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Host:       "db1.example.com",
+		Port:       22,
+		User:       "monitor",
+		CommandID:  id,
+		Command:    "uname -a",
+		DurationMS: 42,
+		BytesOut:   100,
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestLogWritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, 0, 0)
+	require.NoError(t, err)
+
+	e := entry("probe")
+	e.JumpHosts = []string{"ops@bastion:22"}
+	require.NoError(t, l.Log(e))
+	require.NoError(t, l.Log(entry("stats")))
+	require.NoError(t, l.Close())
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 2)
+	var got Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, e, got)
+	assert.Contains(t, lines[1], `"bytes_returned":100`)
+	assert.NotContains(t, lines[1], "jump_hosts")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	assert.Error(t, l.Log(e), "closed logger")
+}
+
+func TestLogRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	line, err := json.Marshal(entry("probe"))
+	require.NoError(t, err)
+
+	// Room for two entries per file, two backups kept
+	l, err := Open(path, int64(2*(len(line)+1)), 2)
+	require.NoError(t, err)
+	defer l.Close()
+	for i := 0; i < 7; i++ {
+		require.NoError(t, l.Log(entry("probe")))
+	}
+
+	assert.Len(t, readLines(t, path), 1)
+	assert.Len(t, readLines(t, path+".1"), 2)
+	assert.Len(t, readLines(t, path+".2"), 2)
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\npartial"), 0o600))
+
+	lines, offset, err := Tail(path, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, lines)
+	assert.Equal(t, int64(len("a\nb\nc\n")), offset)
+
+	lines, _, err = Tail(path, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, lines)
+
+	_, _, err = Tail(filepath.Join(t.TempDir(), "missing.log"), 10)
+	assert.Error(t, err)
+}
+
+func TestFollowAcrossRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	line, err := json.Marshal(entry("probe"))
+	require.NoError(t, err)
+	// Room for two entries, so only the second one followed rotates the log
+	l, err := Open(path, int64(2*(len(line)+1)+8), 1)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Log(entry("before")))
+
+	_, offset, err := Tail(path, 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Follow(ctx, path, offset, func(line string) { got <- line })
+	}()
+
+	// The second entry rotates the log
+	for _, id := range []string{"first", "second"} {
+		require.NoError(t, l.Log(entry(id)))
+		select {
+		case line := <-got:
+			assert.Contains(t, line, `"command_id":"`+id+`"`)
+		case <-ctx.Done():
+			t.Fatalf("entry %s was not followed", id)
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
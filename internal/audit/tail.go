@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// followInterval is how often Follow checks the log for new entries.
+const followInterval = 500 * time.Millisecond
+
+// Tail returns the last n lines of the log at path, oldest first, and the
+// offset just past them for use with Follow.
+func Tail(path string, n int) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			// A partial last line is still being written; Follow picks it up
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		offset += int64(len(line))
+		if n <= 0 {
+			continue
+		}
+		lines = append(lines, line[:len(line)-1])
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, offset, nil
+}
+
+// Follow calls fn with each complete line appended to the log at path after
+// offset, until ctx is done. When the log is rotated, it continues from the
+// start of the new file.
+func Follow(ctx context.Context, path string, offset int64, fn func(line string)) error {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	var (
+		pending []byte
+		last    os.FileInfo
+	)
+	for {
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			// Between the rename and the reopen of a rotation
+		case err != nil:
+			return fmt.Errorf("failed to stat audit log: %w", err)
+		default:
+			if (last != nil && !os.SameFile(last, info)) || info.Size() < offset {
+				offset, pending = 0, nil
+			}
+			last = info
+			if info.Size() > offset {
+				data, err := readFrom(path, offset)
+				if err != nil {
+					return err
+				}
+				offset += int64(len(data))
+				pending = append(pending, data...)
+				for {
+					i := bytes.IndexByte(pending, '\n')
+					if i < 0 {
+						break
+					}
+					fn(string(pending[:i]))
+					pending = pending[i+1:]
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func readFrom(path string, offset int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return data, nil
+}
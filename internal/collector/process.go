@@ -18,7 +18,8 @@ type MonitoredProcess struct {
 	Cmdline   string
 	CPU       float64
 	MEM       float64
-	StartTime string 
+	StartTime time.Time     // when the process started, on the local clock
+	Age       time.Duration // how long the process has been running at collection time
 	Status    string 
 }
 
@@ -30,6 +31,7 @@ func GetFilteredProcesses(filters config.ProcessFilterConfig) ([]MonitoredProces
 	}
 
 	var matches []MonitoredProcess
+	now := time.Now()
 
 	for _, p := range procs {
 		// name, _ := p.Name()
@@ -41,7 +43,7 @@ func GetFilteredProcesses(filters config.ProcessFilterConfig) ([]MonitoredProces
 		username, _ := p.Username()
 		cpuPercent, _ := p.CPUPercent()
 		memPercent, _ := p.MemoryPercent()
-		startTime, _ := p.CreateTime() // returns Unix milliseconds
+		createTime, _ := p.CreateTime() // returns Unix milliseconds
 		statusList, _ := p.Status()
 		status := ""
 		if len(statusList) > 0 {
@@ -49,8 +51,12 @@ func GetFilteredProcesses(filters config.ProcessFilterConfig) ([]MonitoredProces
 		}
 
 
-		start := time.UnixMilli(startTime).Format("15:04:05")
-
+		var start time.Time
+		var age time.Duration
+		if createTime > 0 {
+			start = time.UnixMilli(createTime)
+			age = now.Sub(start)
+		}
 
 		if !matchesKeyword(name, cmdline, filters.Keywords) {
 			continue
@@ -63,11 +69,12 @@ func GetFilteredProcesses(filters config.ProcessFilterConfig) ([]MonitoredProces
 			PID:     p.Pid,
 			User:    username,
 			Name:    name,
-			Cmdline: cmdline,
+			Cmdline: RedactCmdline(cmdline),
 			CPU:       cpuPercent,
 			MEM:       float64(memPercent),
 			Status:    status,
 			StartTime: start,
+			Age:       age,
 		})
 
 
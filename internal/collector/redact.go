@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"sync/atomic"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/redact"
+)
+
+// redactor masks secrets in collected command lines; nil means
+// redact.Default.
+var redactor atomic.Pointer[redact.Redactor]
+
+// SetRedactor replaces the redactor applied to the command lines of every
+// process collected from now on, locally or remotely. A nil r restores the
+// built-in patterns.
+func SetRedactor(r *redact.Redactor) {
+	redactor.Store(r)
+}
+
+// RedactCmdline masks the secrets in cmdline.
+func RedactCmdline(cmdline string) string {
+	r := redactor.Load()
+	if r == nil {
+		r = redact.Default
+	}
+	return r.Redact(cmdline)
+}
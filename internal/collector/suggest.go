@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ignoredProcessNames are processes present on almost every host that make
+// poor monitoring keywords.
+var ignoredProcessNames = map[string]bool{
+	"bash": true, "sh": true, "zsh": true, "fish": true, "dash": true,
+	"login": true, "agetty": true, "sudo": true, "su": true,
+	"tmux": true, "screen": true, "ps": true, "top": true, "less": true,
+	"systemd": true, "init": true, "dbus-daemon": true, "cron": true,
+}
+
+// SuggestProcessKeywords returns up to n names of locally running processes,
+// ranked by combined memory and CPU usage, as candidate process filter
+// keywords. Kernel threads, shells and gosysmesh itself are skipped.
+func SuggestProcessKeywords(n int) ([]string, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	self := ""
+	if exe, err := os.Executable(); err == nil {
+		self = filepath.Base(exe)
+	}
+
+	usage := map[string]float64{}
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil || name == "" || name == self || ignoredProcessNames[name] {
+			continue
+		}
+		// kernel threads have no command line
+		if cmdline, _ := p.Cmdline(); cmdline == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "systemd-") {
+			continue
+		}
+		mem, _ := p.MemoryPercent()
+		cpu, _ := p.CPUPercent()
+		usage[name] += float64(mem) + cpu
+	}
+
+	names := make([]string, 0, len(usage))
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if usage[names[i]] != usage[names[j]] {
+			return usage[names[i]] > usage[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names, nil
+}
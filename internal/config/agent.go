@@ -0,0 +1,56 @@
+package config
+
+import (
+	"regexp"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// Collection modes for a remote target.
+const (
+	ModeScrape = "scrape"
+	ModeAgent  = "agent"
+)
+
+// DefaultAgentPath is where the agent binary is expected when agent_path is
+// unset. Relative paths are resolved by the remote shell from the login
+// user's home directory.
+const DefaultAgentPath = ".gosysmesh/bin/gosysmesh"
+
+// agentPathRegex restricts agent paths to characters that need no quoting in
+// the remote shell, so that a leading ~ is still expanded there.
+var agentPathRegex = regexp.MustCompile(`^[a-zA-Z0-9_./~-]+$`)
+
+// UsesAgent reports whether the target is collected through the agent.
+func (t RemoteTarget) UsesAgent() bool {
+	return t.Mode == ModeAgent
+}
+
+// AgentBinary returns the agent path on the target, or DefaultAgentPath.
+func (t RemoteTarget) AgentBinary() string {
+	if t.AgentPath == "" {
+		return DefaultAgentPath
+	}
+	return t.AgentPath
+}
+
+// validateAgent validates the collection mode and agent path of the target at path.
+func validateAgent(target *RemoteTarget, path string) error {
+	var errs ValidationErrors
+
+	switch target.Mode {
+	case "", ModeScrape, ModeAgent:
+	default:
+		errs.addf(path+".mode", "invalid mode %q (must be %q or %q)", target.Mode, ModeScrape, ModeAgent)
+	}
+
+	if target.AgentPath != "" {
+		if err := validation.FilePath(target.AgentPath); err != nil {
+			errs.addf(path+".agent_path", "invalid agent path: %w", err)
+		} else if !agentPathRegex.MatchString(target.AgentPath) {
+			errs.addf(path+".agent_path", "invalid agent path %q (only alphanumeric, underscore, dot, dash, slash and tilde allowed)", target.AgentPath)
+		}
+	}
+
+	return errs.orNil()
+}
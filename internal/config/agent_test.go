@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAgent(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  RemoteTarget
+		wantErr string
+	}{
+		{"default mode", RemoteTarget{}, ""},
+		{"scrape", RemoteTarget{Mode: ModeScrape}, ""},
+		{"agent with path", RemoteTarget{Mode: ModeAgent, AgentPath: "~/bin/gosysmesh"}, ""},
+		{"unknown mode", RemoteTarget{Mode: "push"}, "invalid mode"},
+		{"path traversal", RemoteTarget{Mode: ModeAgent, AgentPath: "../gosysmesh"}, "path traversal"},
+		{"shell metacharacters", RemoteTarget{Mode: ModeAgent, AgentPath: "gosysmesh;reboot"}, "invalid agent path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAgent(&tt.target, "monitor.remote[0]")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
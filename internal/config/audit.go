@@ -0,0 +1,58 @@
+package config
+
+import "github.com/ChristianThibeault/gosysmesh/internal/validation"
+
+// AuditConfig configures the audit log of remote commands. Auditing is
+// disabled when Path is empty.
+type AuditConfig struct {
+	// Path is the JSON lines file to append to, relative to the config file.
+	Path string `mapstructure:"path"`
+	// MaxSizeMB is the size at which the log is rotated; 0 means 10.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+	// MaxFiles is how many rotated files to keep; 0 means 5.
+	MaxFiles int `mapstructure:"max_files"`
+}
+
+const (
+	defaultAuditMaxSizeMB = 10
+	defaultAuditMaxFiles  = 5
+)
+
+// Enabled reports whether remote commands are audited.
+func (a AuditConfig) Enabled() bool {
+	return a.Path != ""
+}
+
+// MaxSizeBytes returns the rotation size in bytes.
+func (a AuditConfig) MaxSizeBytes() int64 {
+	mb := a.MaxSizeMB
+	if mb == 0 {
+		mb = defaultAuditMaxSizeMB
+	}
+	return int64(mb) << 20
+}
+
+// Backups returns how many rotated files to keep.
+func (a AuditConfig) Backups() int {
+	if a.MaxFiles == 0 {
+		return defaultAuditMaxFiles
+	}
+	return a.MaxFiles
+}
+
+// validateAudit validates the audit block.
+func validateAudit(a *AuditConfig) error {
+	var errs ValidationErrors
+	if a.Path != "" {
+		if err := validation.FilePath(a.Path); err != nil {
+			errs.addf("audit.path", "invalid audit path: %w", err)
+		}
+	}
+	if a.MaxSizeMB < 0 {
+		errs.addf("audit.max_size_mb", "max_size_mb cannot be negative")
+	}
+	if a.MaxFiles < 0 {
+		errs.addf("audit.max_files", "max_files cannot be negative")
+	}
+	return errs.orNil()
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigAudit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gosysmesh.yaml")
+	yaml := `interval: "5s"
+audit:
+  path: "logs/audit.log"
+  max_files: 2
+monitor:
+  local:
+    enabled: true
+`
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+
+	conf, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.True(t, conf.Audit.Enabled())
+	assert.Equal(t, filepath.Join(dir, "logs/audit.log"), conf.Audit.Path, "relative to the config file")
+	assert.Equal(t, int64(10<<20), conf.Audit.MaxSizeBytes())
+	assert.Equal(t, 2, conf.Audit.Backups())
+
+	assert.False(t, AuditConfig{}.Enabled())
+	assert.Equal(t, 5, AuditConfig{}.Backups())
+}
+
+func TestValidateAudit(t *testing.T) {
+	assert.NoError(t, validateAudit(&AuditConfig{}))
+	assert.NoError(t, validateAudit(&AuditConfig{Path: "/var/log/gosysmesh/audit.log", MaxSizeMB: 1, MaxFiles: 1}))
+	assert.Error(t, validateAudit(&AuditConfig{Path: "../audit;log"}))
+	assert.Error(t, validateAudit(&AuditConfig{MaxSizeMB: -1}))
+	assert.Error(t, validateAudit(&AuditConfig{MaxFiles: -1}))
+}
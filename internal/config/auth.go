@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// envNameRegex matches environment variable names.
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AgentSocket returns the ssh-agent socket the target authenticates with:
+// its identity_agent, or else SSH_AUTH_SOCK. It is empty when no agent is
+// available.
+func (t RemoteTarget) AgentSocket() string {
+	if t.IdentityAgent != "" {
+		return t.IdentityAgent
+	}
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+// HasPassphrase reports whether the target's key is encrypted with a
+// passphrase gosysmesh looks up itself.
+func (t RemoteTarget) HasPassphrase() bool {
+	return t.PassphraseFile != "" || t.PassphraseEnv != ""
+}
+
+// validateAuth validates the key, agent, passphrase and certificate
+// settings of the target at path.
+func validateAuth(target *RemoteTarget, path string) error {
+	var errs ValidationErrors
+
+	if target.SSHKey == "" {
+		if target.AgentSocket() == "" {
+			errs.addf(path+".ssh_key", "SSH key path cannot be empty unless identity_agent is set or SSH_AUTH_SOCK is available")
+		}
+	} else if err := validation.FilePath(target.SSHKey); err != nil {
+		errs.addf(path+".ssh_key", "invalid SSH key path: %w", err)
+	}
+
+	if target.IdentityAgent != "" {
+		if err := validation.FilePath(target.IdentityAgent); err != nil {
+			errs.addf(path+".identity_agent", "invalid agent socket path: %w", err)
+		}
+	}
+
+	if target.PassphraseFile != "" && target.PassphraseEnv != "" {
+		errs.addf(path+".passphrase_file", "passphrase_file and passphrase_env cannot both be set")
+	}
+	if target.HasPassphrase() && target.SSHKey == "" {
+		errs.addf(path+".ssh_key", "a passphrase needs the ssh_key it unlocks")
+	}
+	if target.PassphraseFile != "" {
+		if err := validation.FilePath(target.PassphraseFile); err != nil {
+			errs.addf(path+".passphrase_file", "invalid passphrase file path: %w", err)
+		}
+	}
+	if target.PassphraseEnv != "" && !envNameRegex.MatchString(target.PassphraseEnv) {
+		errs.addf(path+".passphrase_env", "invalid environment variable name %q", target.PassphraseEnv)
+	}
+
+	if target.Certificate != "" {
+		if err := validation.FilePath(target.Certificate); err != nil {
+			errs.addf(path+".certificate", "invalid certificate path: %w", err)
+		}
+	}
+
+	return errs.orNil()
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		agent   string
+		target  RemoteTarget
+		wantErr string
+	}{
+		{"key", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519"}, ""},
+		{"no key and no agent", "", RemoteTarget{}, "cannot be empty"},
+		{"no key with SSH_AUTH_SOCK", "/tmp/agent.sock", RemoteTarget{}, ""},
+		{"no key with identity_agent", "", RemoteTarget{IdentityAgent: "~/.1password/agent.sock"}, ""},
+		{"passphrase env", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", PassphraseEnv: "KEY_PASS"}, ""},
+		{"passphrase file", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", PassphraseFile: "~/.gosysmesh/key.pass"}, ""},
+		{"both passphrase sources", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", PassphraseEnv: "KEY_PASS", PassphraseFile: "~/key.pass"}, "cannot both be set"},
+		{"passphrase without key", "/tmp/agent.sock", RemoteTarget{PassphraseEnv: "KEY_PASS"}, "needs the ssh_key"},
+		{"invalid env name", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", PassphraseEnv: "KEY-PASS"}, "invalid environment variable name"},
+		{"certificate", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", Certificate: "~/.ssh/id_ed25519-cert.pub"}, ""},
+		{"traversing certificate path", "", RemoteTarget{SSHKey: "~/.ssh/id_ed25519", Certificate: "../../etc/cert.pub"}, "invalid certificate path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SSH_AUTH_SOCK", tt.agent)
+			err := validateAuth(&tt.target, "monitor.remote[0]")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,10 +2,11 @@ package config
 
 import (
 	"fmt"
-	"regexp"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
 	"github.com/spf13/viper"
 )
 
@@ -17,9 +18,15 @@ type ProcessFilterConfig struct {
 }
 
 // LocalMonitorConfig defines the configuration for local process monitoring.
+// A local block without an enabled key is enabled; without a local block,
+// only remote targets are monitored.
 type LocalMonitorConfig struct {
     Enabled        bool                `mapstructure:"enabled"`
     ProcessFilters ProcessFilterConfig `mapstructure:"process_filters"`
+	// Interval and Intervals override the top-level interval for local
+	// collection, for all metric families or per family.
+	Interval  string            `mapstructure:"interval,omitempty"`
+	Intervals map[string]string `mapstructure:"intervals,omitempty"`
 }
 
 // RemoteTarget defines the configuration for remote process monitoring targets.
@@ -29,11 +36,63 @@ type RemoteTarget struct {
     Port           int                 `mapstructure:"port"`
 	SSHKey         string              `mapstructure:"ssh_key"` 
 	ProxyJump      string         	   `mapstructure:"proxy_jump,omitempty"`
+	// JumpHosts is the full form of ProxyJump, allowing each hop to use its
+	// own user, port and key. Hops are listed from the first to the last.
+	JumpHosts      []JumpConfig        `mapstructure:"jump_hosts,omitempty"`
     ProcessFilters ProcessFilterConfig `mapstructure:"process_filters"`
+	// MaxClockSkew, when set, raises an alert if the remote clock drifts
+	// further than this duration from the local clock (e.g. "2s").
+	MaxClockSkew string `mapstructure:"max_clock_skew,omitempty"`
+	// Group names an entry in the top-level groups block to inherit settings from.
+	Group string   `mapstructure:"group,omitempty"`
+	Tags  []string `mapstructure:"tags,omitempty"`
+	// Mode selects how the target is collected: "scrape" (the default) parses
+	// ps and top output, "agent" runs a gosysmesh binary on the host.
+	Mode string `mapstructure:"mode,omitempty"`
+	// AgentPath is where the gosysmesh binary lives on the host in agent mode.
+	AgentPath string `mapstructure:"agent_path,omitempty"`
+	// HostKeyPolicy is "strict" (the default), "tofu" or "pinned".
+	HostKeyPolicy string `mapstructure:"host_key_policy,omitempty"`
+	// HostKeyFingerprints are the SHA256 fingerprints accepted with the
+	// pinned policy. They are never inherited.
+	HostKeyFingerprints []string `mapstructure:"host_key_fingerprints,omitempty"`
+	// IdentityAgent is the ssh-agent socket to authenticate with instead of
+	// SSH_AUTH_SOCK. With an agent available, SSHKey may be left unset.
+	IdentityAgent string `mapstructure:"identity_agent,omitempty"`
+	// PassphraseFile and PassphraseEnv name where the passphrase of an
+	// encrypted SSHKey is read from: a file, or an environment variable.
+	PassphraseFile string `mapstructure:"passphrase_file,omitempty"`
+	PassphraseEnv  string `mapstructure:"passphrase_env,omitempty"`
+	// Certificate is the OpenSSH certificate presented with the key, by
+	// default the key path with -cert.pub appended, if it exists.
+	Certificate string `mapstructure:"certificate,omitempty"`
+	// Enabled set to false parks the target: it stays in the configuration
+	// but is not collected, diagnosed or deployed to. Unset means enabled.
+	Enabled *bool `mapstructure:"enabled,omitempty"`
+	// Interval and Intervals override the top-level interval for this
+	// target, for all metric families or per family (e.g. processes: 5s).
+	Interval  string            `mapstructure:"interval,omitempty"`
+	Intervals map[string]string `mapstructure:"intervals,omitempty"`
 }
 
+// IsEnabled reports whether the target is collected; see Enabled.
+func (t RemoteTarget) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
 
-// JumpConfig defines the configuration for SSH jump hosts.
+// ClockSkewThreshold returns the parsed MaxClockSkew, or zero if it is unset.
+func (t RemoteTarget) ClockSkewThreshold() time.Duration {
+	d, err := time.ParseDuration(t.MaxClockSkew)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+
+// JumpConfig defines the configuration for SSH jump hosts. An unset user
+// defaults to the target's user, an unset port to 22, and an unset key to
+// whatever the SSH client would use on its own.
 type JumpConfig struct {
     Host       string `mapstructure:"host"`
     User       string `mapstructure:"user"`
@@ -50,37 +109,78 @@ type MonitorConfig struct {
 
 // Config structure for the gosysmesh application.
 type Config struct {
-    Interval string        `mapstructure:"interval"`
-    Monitor  MonitorConfig `mapstructure:"monitor"`
+    Interval  string                    `mapstructure:"interval"`
+	// Intervals sets the default interval per metric family, and Jitter the
+	// largest random delay added to each collection (default: a tenth of
+	// its interval).
+	Intervals map[string]string `mapstructure:"intervals"`
+	Jitter    string            `mapstructure:"jitter"`
+    Defaults  TargetDefaults            `mapstructure:"defaults"`
+    Groups    map[string]TargetDefaults `mapstructure:"groups"`
+    Monitor   MonitorConfig             `mapstructure:"monitor"`
+    Inventory InventoryConfig           `mapstructure:"inventory"`
+    Audit     AuditConfig               `mapstructure:"audit"`
+    Redact    RedactConfig              `mapstructure:"redact"`
 }
 
 // LoadConfig reads the configuration from a YAML file and unmarshals it into a Config struct.
+// Environment variables starting with EnvPrefix and --set overrides take
+// precedence over the file, in that order. An empty path loads the file found
+// by the CLI's config search.
+// Validation problems are returned together as ValidationErrors, annotated
+// with the line they appear on where the file has one.
 func LoadConfig(path string) (*Config, error) {
-	viper.SetConfigFile(path)
-	viper.SetConfigType("yaml")
+	if path == "" {
+		path = viper.ConfigFileUsed()
+	}
+
+	// A fresh instance per load, so that overrides set for one load do not
+	// mask the file on the next reload
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("error reading config: %w", err)
 	}
 
+	overrides := overrides()
+	if err := applyOverrides(v, overrides); err != nil {
+		return nil, fmt.Errorf("invalid config override: %w", err)
+	}
+
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("unable to decode into struct: %w", err)
 	}
 
-	if !viper.IsSet("monitor.local") {
-    return nil, fmt.Errorf("missing required field: monitor.local")
+	// A local block written before the enabled key existed stays enabled
+	if v.IsSet("monitor.local") && !v.IsSet("monitor.local.enabled") {
+		config.Monitor.Local.Enabled = true
 	}
 
+	var errs ValidationErrors
 
-
-	if _, err := time.ParseDuration(config.Interval); err != nil {
-		return nil, fmt.Errorf("invalid interval format: %s", config.Interval)
+	// Expand inventory sources into remote targets before validating them
+	if err := expandInventory(&config, filepath.Dir(v.ConfigFileUsed())); err != nil {
+		return nil, fmt.Errorf("failed to expand inventory: %w", err)
 	}
 
+	// Fill unset target settings from groups and defaults
+	errs.add("monitor.remote", applyInheritance(&config))
+
 	// Validate configuration for security
-	if err := validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	errs.add("", validateConfig(&config))
+
+	if len(errs) > 0 {
+		annotateLines(v.ConfigFileUsed(), errs)
+		markOverridden(errs, overrides)
+		return nil, fmt.Errorf("configuration validation failed: %w", errs)
+	}
+
+	// The audit log path is relative to the config file
+	if config.Audit.Path != "" {
+		config.Audit.Path = resolveConfigPath(config.Audit.Path, filepath.Dir(v.ConfigFileUsed()))
 	}
 
 	return &config, nil
@@ -88,156 +188,132 @@ func LoadConfig(path string) (*Config, error) {
 
 // validateConfig validates configuration parameters for security
 func validateConfig(config *Config) error {
+	var errs ValidationErrors
+
 	// Validate interval
 	duration, err := time.ParseDuration(config.Interval)
 	if err != nil {
-		return fmt.Errorf("invalid interval: %w", err)
+		errs.addf("interval", "invalid interval format: %q", config.Interval)
+	} else if duration < time.Second || duration > 24*time.Hour {
+		errs.addf("interval", "interval must be between 1 second and 24 hours")
 	}
-	if duration < time.Second || duration > 24*time.Hour {
-		return fmt.Errorf("interval must be between 1 second and 24 hours")
+
+	// Validate groups
+	for name, group := range config.Groups {
+		path := "groups." + name
+		if !tagRegex.MatchString(name) {
+			errs.addf(path, "invalid group name %q", name)
+		}
+		errs.add(path+".tags", validateTags(group.Tags))
 	}
+	errs.add("defaults.tags", validateTags(config.Defaults.Tags))
+
+	// Validate per-target and per-family intervals
+	errs.add("", validateIntervals("", config.Intervals, ""))
+	errs.add("jitter", validateJitter(config.Jitter))
+	errs.add("", validateIntervals(config.Monitor.Local.Interval, config.Monitor.Local.Intervals, "monitor.local"))
 
 	// Validate remote targets
 	for i, target := range config.Monitor.Remote {
-		if err := validateRemoteTarget(&target, i); err != nil {
-			return fmt.Errorf("remote target %d validation failed: %w", i, err)
-		}
+		errs.add("", validateRemoteTarget(&target, i))
 	}
+	if !config.Monitor.Local.Enabled && len(config.FilterByTags(nil)) == 0 {
+		errs.addf("monitor", "nothing to monitor: enable monitor.local or add an enabled monitor.remote target")
+	}
+
+	// Validate audit log settings
+	errs.add("", validateAudit(&config.Audit))
+
+	// Validate secret redaction patterns
+	errs.add("", validateRedact(&config.Redact))
 
 	// Validate local process filters
-	if err := validateProcessFilters(&config.Monitor.Local.ProcessFilters); err != nil {
-		return fmt.Errorf("local process filters validation failed: %w", err)
-	}
+	errs.add("", validateProcessFilters(&config.Monitor.Local.ProcessFilters, "monitor.local.process_filters"))
 
-	return nil
+	return errs.orNil()
 }
 
 // validateRemoteTarget validates a remote target configuration
 func validateRemoteTarget(target *RemoteTarget, index int) error {
+	var errs ValidationErrors
+	path := fmt.Sprintf("monitor.remote[%d]", index)
+
 	// Validate hostname
 	if target.Host == "" {
-		return fmt.Errorf("host cannot be empty")
-	}
-	if err := validateHostname(target.Host); err != nil {
-		return fmt.Errorf("invalid host: %w", err)
+		errs.addf(path+".host", "host cannot be empty")
+	} else if err := validation.Host(target.Host); err != nil {
+		errs.addf(path+".host", "invalid host %q: %w", target.Host, err)
 	}
 
 	// Validate username
 	if target.User == "" {
-		return fmt.Errorf("user cannot be empty")
-	}
-	if err := validateUsername(target.User); err != nil {
-		return fmt.Errorf("invalid user: %w", err)
+		errs.addf(path+".user", "user cannot be empty")
+	} else if err := validation.Username(target.User); err != nil {
+		errs.addf(path+".user", "invalid user: %w", err)
 	}
 
 	// Validate port
 	if target.Port < 1 || target.Port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535")
+		errs.addf(path+".port", "port must be between 1 and 65535")
 	}
 
-	// Validate SSH key path
-	if target.SSHKey == "" {
-		return fmt.Errorf("SSH key path cannot be empty")
-	}
-	if err := validateFilePath(target.SSHKey); err != nil {
-		return fmt.Errorf("invalid SSH key path: %w", err)
-	}
+	// Validate SSH key, agent, passphrase and certificate settings
+	errs.add("", validateAuth(target, path))
 
-	// Validate proxy jump if provided
-	if target.ProxyJump != "" {
-		if err := validateHostname(target.ProxyJump); err != nil {
-			return fmt.Errorf("invalid proxy jump host: %w", err)
-		}
-	}
+	// Validate jump hosts if provided
+	errs.add("", validateJumpChain(target, path))
 
 	// Validate process filters
-	if err := validateProcessFilters(&target.ProcessFilters); err != nil {
-		return fmt.Errorf("process filters validation failed: %w", err)
+	errs.add("", validateProcessFilters(&target.ProcessFilters, path+".process_filters"))
+
+	// Validate tags
+	errs.add(path+".tags", validateTags(target.Tags))
+
+	// Validate collection mode and agent path
+	errs.add("", validateAgent(target, path))
+	errs.add("", validateHostKeyPolicy(target, path))
+	errs.add("", validateIntervals(target.Interval, target.Intervals, path))
+
+	// Validate clock skew threshold if provided
+	if target.MaxClockSkew != "" {
+		d, err := time.ParseDuration(target.MaxClockSkew)
+		if err != nil {
+			errs.addf(path+".max_clock_skew", "invalid max_clock_skew: %w", err)
+		} else if d <= 0 {
+			errs.addf(path+".max_clock_skew", "max_clock_skew must be positive")
+		}
 	}
 
-	return nil
+	return errs.orNil()
 }
 
-// validateProcessFilters validates process filter configuration
-func validateProcessFilters(filters *ProcessFilterConfig) error {
+// validateProcessFilters validates process filter configuration found at path
+func validateProcessFilters(filters *ProcessFilterConfig, path string) error {
+	var errs ValidationErrors
+
 	// Validate keywords
 	for i, keyword := range filters.Keywords {
+		kwPath := fmt.Sprintf("%s.keywords[%d]", path, i)
 		if keyword == "" {
-			return fmt.Errorf("keyword %d cannot be empty", i)
+			errs.addf(kwPath, "keyword cannot be empty")
+			continue
 		}
 		if len(keyword) > 100 {
-			return fmt.Errorf("keyword %d too long (max 100 characters)", i)
+			errs.addf(kwPath, "keyword too long (max 100 characters)")
 		}
 		// Prevent dangerous patterns in keywords
 		if strings.ContainsAny(keyword, ";&|$`\n\r") {
-			return fmt.Errorf("keyword %d contains dangerous characters", i)
+			errs.addf(kwPath, "keyword contains dangerous characters")
 		}
 	}
 
 	// Validate users
 	for i, user := range filters.Users {
-		if err := validateUsername(user); err != nil {
-			return fmt.Errorf("user %d validation failed: %w", i, err)
+		if err := validation.Username(user); err != nil {
+			errs.addf(fmt.Sprintf("%s.users[%d]", path, i), "invalid user: %w", err)
 		}
 	}
 
-	return nil
-}
-
-// validateHostname validates hostname format
-func validateHostname(host string) error {
-	if host == "" {
-		return fmt.Errorf("hostname cannot be empty")
-	}
-	if len(host) > 253 {
-		return fmt.Errorf("hostname too long")
-	}
-
-	// Allow hostname format (RFC 1123) or IP address
-	hostnameRegex := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
-	
-	// More strict IP validation
-	ipRegex := regexp.MustCompile(`^(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)$`)
-
-	if !hostnameRegex.MatchString(host) && !ipRegex.MatchString(host) {
-		return fmt.Errorf("invalid hostname or IP address format")
-	}
-
-	return nil
+	return errs.orNil()
 }
 
-// validateUsername validates username format
-func validateUsername(user string) error {
-	if user == "" {
-		return fmt.Errorf("username cannot be empty")
-	}
-	if len(user) > 32 {
-		return fmt.Errorf("username too long (max 32 characters)")
-	}
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(user) {
-		return fmt.Errorf("invalid username format (only alphanumeric, underscore, dash allowed)")
-	}
-	return nil
-}
-
-// validateFilePath validates file path format
-func validateFilePath(path string) error {
-	if path == "" {
-		return fmt.Errorf("file path cannot be empty")
-	}
-	if len(path) > 4096 {
-		return fmt.Errorf("file path too long")
-	}
-
-	// Prevent path traversal attacks more strictly
-	if strings.Contains(path, "..") {
-		return fmt.Errorf("path traversal not allowed")
-	}
-
-	// Prevent null bytes and other dangerous characters
-	if strings.ContainsAny(path, "\x00\n\r") {
-		return fmt.Errorf("file path contains dangerous characters")
-	}
-
-	return nil
-}
@@ -14,7 +14,8 @@ func TestEdgeCaseConfigs(t *testing.T) {
         wantErr  bool
     }{
         {"MissingInterval", "missing_interval.yaml", true},
-        {"MissingMonitorLocal", "missing_monitor_local.yaml", true},
+        {"NothingToMonitor", "missing_monitor_local.yaml", true},
+        {"RemoteOnly", "remote_only.yaml", false},
         {"UnquotedInterval", "unquoted_interval.yaml", true},
         {"InvalidYAML", "invalid_yaml.yaml", true},
         {"NonExistentFile", "does_not_exist.yaml", true},
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// ValidationError describes one invalid configuration value.
+type ValidationError struct {
+	Path   string // YAML path of the value, e.g. monitor.remote[2].user
+	Line   int    // line in the config file, 0 if unknown
+	Source string // environment variable or flag that set the value, if any
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Source != "":
+		return fmt.Sprintf("%s (from %s): %v", e.Path, e.Source, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%s (line %d): %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects every problem found in a configuration.
+type ValidationErrors []*ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add records err at path, if err is not nil. Nested ValidationErrors keep
+// their own paths, and the path of a validation.FieldError is appended to
+// path.
+func (v *ValidationErrors) add(path string, err error) {
+	if err == nil {
+		return
+	}
+	if nested, ok := err.(ValidationErrors); ok {
+		*v = append(*v, nested...)
+		return
+	}
+	if fe, ok := err.(*validation.FieldError); ok {
+		path, err = validation.JoinPath(path, fe.Path), fe.Err
+	}
+	*v = append(*v, &ValidationError{Path: path, Err: err})
+}
+
+// addf records a formatted error at path.
+func (v *ValidationErrors) addf(path, format string, args ...interface{}) {
+	*v = append(*v, &ValidationError{Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// orNil returns v as an error, or nil when it is empty, so callers never see
+// a non-nil error holding no problems.
+func (v ValidationErrors) orNil() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigReportsEveryProblem(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, err := LoadConfig("../../test_configs/multiple_errors.yaml")
+	require.Error(t, err)
+
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+
+	type problem struct {
+		path string
+		line int
+	}
+	var got []problem
+	for _, e := range verrs {
+		got = append(got, problem{e.Path, e.Line})
+	}
+
+	assert.Equal(t, []problem{
+		{"interval", 1},
+		{"monitor.remote[0].host", 6},
+		{"monitor.remote[1].user", 8},
+		{"monitor.remote[1].port", 8},
+		{"monitor.remote[1].ssh_key", 8},
+		{"monitor.remote[1].group", 9},
+		{"monitor.remote[1].process_filters.keywords[0]", 11},
+		{"monitor.remote[1].process_filters.keywords[1]", 11},
+	}, got)
+}
+
+func TestSplitPath(t *testing.T) {
+	assert.Equal(t, []string{"monitor", "remote", "[2]", "jump_hosts", "[0]"}, splitPath("monitor.remote[2].jump_hosts[0]"))
+	assert.Equal(t, []string{"interval"}, splitPath("interval"))
+}
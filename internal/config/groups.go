@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TargetDefaults holds settings that remote targets inherit when they leave
+// them unset. It is used both for the top-level defaults block and for groups.
+type TargetDefaults struct {
+	User           string              `mapstructure:"user"`
+	Port           int                 `mapstructure:"port"`
+	SSHKey         string              `mapstructure:"ssh_key"`
+	ProxyJump      string              `mapstructure:"proxy_jump"`
+	JumpHosts      []JumpConfig        `mapstructure:"jump_hosts"`
+	MaxClockSkew   string              `mapstructure:"max_clock_skew"`
+	ProcessFilters ProcessFilterConfig `mapstructure:"process_filters"`
+	Tags           []string            `mapstructure:"tags"`
+	Mode           string              `mapstructure:"mode"`
+	AgentPath      string              `mapstructure:"agent_path"`
+	HostKeyPolicy  string              `mapstructure:"host_key_policy"`
+	IdentityAgent  string              `mapstructure:"identity_agent"`
+	PassphraseFile string              `mapstructure:"passphrase_file"`
+	PassphraseEnv  string              `mapstructure:"passphrase_env"`
+	Certificate    string              `mapstructure:"certificate"`
+	Interval       string              `mapstructure:"interval"`
+	Intervals      map[string]string   `mapstructure:"intervals"`
+}
+
+// defaultSSHPort is used when neither a target nor its group or defaults set a port.
+const defaultSSHPort = 22
+
+var tagRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// applyInheritance fills unset target settings from the target's group and
+// then from the top-level defaults. Tags accumulate across all three levels.
+func applyInheritance(config *Config) error {
+	var errs ValidationErrors
+	for i := range config.Monitor.Remote {
+		target := &config.Monitor.Remote[i]
+
+		layers := []TargetDefaults{}
+		if target.Group != "" {
+			// viper lower-cases map keys
+			group, ok := config.Groups[strings.ToLower(target.Group)]
+			if !ok {
+				errs.addf(fmt.Sprintf("monitor.remote[%d].group", i), "unknown group %q", target.Group)
+				continue
+			}
+			layers = append(layers, group)
+		}
+		layers = append(layers, config.Defaults)
+
+		for _, layer := range layers {
+			inherit(target, layer)
+		}
+		if target.Port == 0 {
+			target.Port = defaultSSHPort
+		}
+	}
+	return errs.orNil()
+}
+
+// inherit copies each setting from d that target does not already set.
+func inherit(target *RemoteTarget, d TargetDefaults) {
+	if target.User == "" {
+		target.User = d.User
+	}
+	if target.Port == 0 {
+		target.Port = d.Port
+	}
+	if target.SSHKey == "" {
+		target.SSHKey = d.SSHKey
+	}
+	if target.ProxyJump == "" && len(target.JumpHosts) == 0 {
+		target.ProxyJump = d.ProxyJump
+		target.JumpHosts = d.JumpHosts
+	}
+	if target.MaxClockSkew == "" {
+		target.MaxClockSkew = d.MaxClockSkew
+	}
+	if target.ProcessFilters.isEmpty() {
+		target.ProcessFilters = d.ProcessFilters
+	}
+	if target.Mode == "" {
+		target.Mode = d.Mode
+	}
+	if target.AgentPath == "" {
+		target.AgentPath = d.AgentPath
+	}
+	if target.HostKeyPolicy == "" {
+		target.HostKeyPolicy = d.HostKeyPolicy
+	}
+	if target.IdentityAgent == "" {
+		target.IdentityAgent = d.IdentityAgent
+	}
+	// A passphrase or certificate belongs to a key, so it is only inherited
+	// along with the key
+	if target.SSHKey == d.SSHKey && !target.HasPassphrase() {
+		target.PassphraseFile = d.PassphraseFile
+		target.PassphraseEnv = d.PassphraseEnv
+	}
+	if target.SSHKey == d.SSHKey && target.Certificate == "" {
+		target.Certificate = d.Certificate
+	}
+	if target.Interval == "" {
+		target.Interval = d.Interval
+	}
+	// Per-family intervals merge, the nearest level winning for each family
+	for family, interval := range d.Intervals {
+		if _, ok := target.Intervals[family]; !ok {
+			if target.Intervals == nil {
+				target.Intervals = make(map[string]string)
+			}
+			target.Intervals[family] = interval
+		}
+	}
+	for _, tag := range d.Tags {
+		if !stringInSlice(tag, target.Tags) {
+			target.Tags = append(target.Tags, tag)
+		}
+	}
+}
+
+func (f ProcessFilterConfig) isEmpty() bool {
+	return len(f.Keywords) == 0 && len(f.Users) == 0 && len(f.Groups) == 0
+}
+
+// HasAnyTag reports whether the target carries any of tags. The target's
+// group name counts as a tag. An empty tag list matches every target.
+func (t RemoteTarget) HasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if strings.EqualFold(tag, t.Group) {
+			return true
+		}
+		for _, own := range t.Tags {
+			if strings.EqualFold(tag, own) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterByTags returns the enabled remote targets carrying any of tags.
+func (c *Config) FilterByTags(tags []string) []RemoteTarget {
+	var targets []RemoteTarget
+	for _, t := range c.Monitor.Remote {
+		if t.IsEnabled() && t.HasAnyTag(tags) {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// validateTags validates tag names
+func validateTags(tags []string) error {
+	for i, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("tag %d cannot be empty", i)
+		}
+		if len(tag) > 64 {
+			return fmt.Errorf("tag %d too long (max 64 characters)", i)
+		}
+		if !tagRegex.MatchString(tag) {
+			return fmt.Errorf("tag %d has invalid format (only alphanumeric, underscore, dot, dash allowed)", i)
+		}
+	}
+	return nil
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
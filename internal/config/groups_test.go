@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigAppliesGroupsAndDefaults(t *testing.T) {
+	conf, err := LoadConfig("../../test_configs/groups.yaml")
+	require.NoError(t, err)
+	require.Len(t, conf.Monitor.Remote, 4)
+
+	db1, db2, web1, misc := conf.Monitor.Remote[0], conf.Monitor.Remote[1], conf.Monitor.Remote[2], conf.Monitor.Remote[3]
+
+	assert.Equal(t, "postgres", db1.User)
+	assert.Equal(t, 2222, db1.Port)
+	assert.Equal(t, "~/.ssh/id_ed25519", db1.SSHKey)
+	assert.Equal(t, []string{"postgres"}, db1.ProcessFilters.Keywords)
+	assert.Equal(t, []string{"postgres", "prod"}, db1.Tags)
+	assert.True(t, db1.UsesAgent())
+	assert.Equal(t, DefaultAgentPath, db1.AgentBinary())
+
+	assert.Equal(t, "admin", db2.User, "target settings override the group")
+	assert.Equal(t, []string{"replica", "postgres", "prod"}, db2.Tags)
+
+	assert.Equal(t, "monitor", web1.User)
+	assert.Equal(t, 22, web1.Port)
+	assert.Equal(t, "2s", web1.MaxClockSkew)
+	assert.False(t, web1.UsesAgent())
+	assert.Equal(t, []string{"nginx"}, web1.ProcessFilters.Keywords)
+
+	assert.Equal(t, []string{"sshd"}, misc.ProcessFilters.Keywords)
+
+	assert.Len(t, conf.FilterByTags(nil), 4)
+	assert.Len(t, conf.FilterByTags([]string{"postgres"}), 2)
+	assert.Len(t, conf.FilterByTags([]string{"web"}), 1, "group names act as tags")
+	assert.Len(t, conf.FilterByTags([]string{"replica", "web"}), 2)
+}
+
+func TestApplyInheritanceUnknownGroup(t *testing.T) {
+	conf := &Config{Monitor: MonitorConfig{Remote: []RemoteTarget{{Host: "a", Group: "missing"}}}}
+	assert.Error(t, applyInheritance(conf))
+}
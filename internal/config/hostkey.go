@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Host key policies for a remote target.
+const (
+	// HostKeyStrict only accepts hosts already in the user's known_hosts.
+	HostKeyStrict = "strict"
+	// HostKeyTOFU records a host's key on first connection in the
+	// gosysmesh-managed known_hosts and refuses it if it later changes.
+	HostKeyTOFU = "tofu"
+	// HostKeyPinned only accepts keys whose fingerprint is listed in
+	// host_key_fingerprints.
+	HostKeyPinned = "pinned"
+)
+
+// fingerprintRegex matches OpenSSH SHA256 fingerprints, as printed by
+// ssh-keygen -l.
+var fingerprintRegex = regexp.MustCompile(`^SHA256:[A-Za-z0-9+/]{43}$`)
+
+// KeyPolicy returns the target's host key policy, HostKeyStrict if unset.
+func (t RemoteTarget) KeyPolicy() string {
+	if t.HostKeyPolicy == "" {
+		return HostKeyStrict
+	}
+	return t.HostKeyPolicy
+}
+
+// validateHostKeyPolicy validates the host key policy and pinned
+// fingerprints of the target at path.
+func validateHostKeyPolicy(target *RemoteTarget, path string) error {
+	var errs ValidationErrors
+
+	switch target.HostKeyPolicy {
+	case "", HostKeyStrict, HostKeyTOFU, HostKeyPinned:
+	default:
+		errs.addf(path+".host_key_policy", "invalid host key policy %q (must be %q, %q or %q)",
+			target.HostKeyPolicy, HostKeyStrict, HostKeyTOFU, HostKeyPinned)
+	}
+
+	if target.HostKeyPolicy == HostKeyPinned && len(target.HostKeyFingerprints) == 0 {
+		errs.addf(path+".host_key_fingerprints", "pinned host key policy needs at least one fingerprint")
+	}
+	if target.HostKeyPolicy != HostKeyPinned && len(target.HostKeyFingerprints) > 0 {
+		errs.addf(path+".host_key_fingerprints", "fingerprints are only used with host_key_policy %q", HostKeyPinned)
+	}
+	for i, fp := range target.HostKeyFingerprints {
+		if !fingerprintRegex.MatchString(fp) {
+			errs.addf(fmt.Sprintf("%s.host_key_fingerprints[%d]", path, i), "invalid fingerprint %q (want SHA256:<base64>, as printed by ssh-keygen -l)", fp)
+		}
+	}
+
+	return errs.orNil()
+}
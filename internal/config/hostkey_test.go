@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHostKeyPolicy(t *testing.T) {
+	const fp = "SHA256:nuomRbeBRLVUS8wBufqOZzQSFcMycPnzT0R4iVELpBI"
+	tests := []struct {
+		name    string
+		target  RemoteTarget
+		wantErr string
+	}{
+		{"default policy", RemoteTarget{}, ""},
+		{"tofu", RemoteTarget{HostKeyPolicy: HostKeyTOFU}, ""},
+		{"pinned", RemoteTarget{HostKeyPolicy: HostKeyPinned, HostKeyFingerprints: []string{fp}}, ""},
+		{"unknown policy", RemoteTarget{HostKeyPolicy: "ask"}, "invalid host key policy"},
+		{"pinned without fingerprints", RemoteTarget{HostKeyPolicy: HostKeyPinned}, "at least one fingerprint"},
+		{"fingerprints without pinning", RemoteTarget{HostKeyPolicy: HostKeyTOFU, HostKeyFingerprints: []string{fp}}, "only used with"},
+		{"MD5 fingerprint", RemoteTarget{HostKeyPolicy: HostKeyPinned, HostKeyFingerprints: []string{"MD5:16:27:ac:a5:76:28:2d:36:63:1b:56:4d:eb:df:a6:48"}}, "invalid fingerprint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHostKeyPolicy(&tt.target, "monitor.remote[0]")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	target := RemoteTarget{}
+	assert.Equal(t, HostKeyStrict, target.KeyPolicy())
+}
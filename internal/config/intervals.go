@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// MetricFamily names a group of metrics that is collected on its own
+// schedule.
+type MetricFamily string
+
+// Metric families, in the order they are collected and printed.
+const (
+	FamilyProcesses MetricFamily = "processes" // filtered process list
+	FamilySystem    MetricFamily = "system"    // CPU, memory and clock skew
+	FamilyDisk      MetricFamily = "disk"      // root filesystem usage
+)
+
+// MetricFamilies lists every metric family.
+var MetricFamilies = []MetricFamily{FamilyProcesses, FamilySystem, FamilyDisk}
+
+// Interval bounds, shared by the top-level, per-target and per-family settings.
+const (
+	minInterval = time.Second
+	maxInterval = 24 * time.Hour
+	maxJitter   = time.Hour
+)
+
+// IntervalFor returns how often family is collected from target: the
+// target's setting for the family, else its interval, else the top-level
+// setting for the family, else the top-level interval. Groups and defaults
+// have already been folded into the target.
+func (c *Config) IntervalFor(target RemoteTarget, family MetricFamily) time.Duration {
+	return firstDuration(target.Intervals[string(family)], target.Interval, c.Intervals[string(family)], c.Interval)
+}
+
+// LocalIntervalFor returns how often family is collected locally, resolved
+// like IntervalFor.
+func (c *Config) LocalIntervalFor(family MetricFamily) time.Duration {
+	local := c.Monitor.Local
+	return firstDuration(local.Intervals[string(family)], local.Interval, c.Intervals[string(family)], c.Interval)
+}
+
+// JitterFor returns the largest random delay added to each run of a
+// collection scheduled every interval: the jitter setting, or a tenth of
+// the interval when it is unset. It never exceeds the interval, which would
+// space the runs by the jitter rather than the interval.
+func (c *Config) JitterFor(interval time.Duration) time.Duration {
+	if c.Jitter == "" {
+		return interval / 10
+	}
+	d, _ := time.ParseDuration(c.Jitter)
+	if d > interval {
+		return interval
+	}
+	return d
+}
+
+// firstDuration parses the first non-empty value, or returns zero.
+func firstDuration(values ...string) time.Duration {
+	for _, v := range values {
+		if v != "" {
+			d, _ := time.ParseDuration(v)
+			return d
+		}
+	}
+	return 0
+}
+
+// checkInterval validates a collection interval.
+func checkInterval(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid interval format: %q", value)
+	}
+	if d < minInterval || d > maxInterval {
+		return fmt.Errorf("interval must be between %s and %s", minInterval, maxInterval)
+	}
+	return nil
+}
+
+// validateIntervals validates an interval setting and its per-family
+// overrides, found at path.
+func validateIntervals(interval string, intervals map[string]string, path string) error {
+	var errs ValidationErrors
+	if interval != "" {
+		errs.add(validation.JoinPath(path, "interval"), checkInterval(interval))
+	}
+
+	// Sort the families so problems are reported in a stable order
+	families := make([]string, 0, len(intervals))
+	for family := range intervals {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	for _, family := range families {
+		familyPath := validation.JoinPath(path, "intervals."+family)
+		if !isMetricFamily(family) {
+			errs.addf(familyPath, "unknown metric family %q (must be one of %v)", family, MetricFamilies)
+			continue
+		}
+		errs.add(familyPath, checkInterval(intervals[family]))
+	}
+	return errs.orNil()
+}
+
+// validateJitter validates the top-level jitter setting.
+func validateJitter(jitter string) error {
+	if jitter == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(jitter)
+	if err != nil {
+		return fmt.Errorf("invalid jitter format: %q", jitter)
+	}
+	if d < 0 || d > maxJitter {
+		return fmt.Errorf("jitter must be between 0s and %s", maxJitter)
+	}
+	return nil
+}
+
+func isMetricFamily(name string) bool {
+	for _, f := range MetricFamilies {
+		if string(f) == name {
+			return true
+		}
+	}
+	return false
+}
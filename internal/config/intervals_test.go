@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalFor(t *testing.T) {
+	conf, err := LoadConfig("../../test_configs/intervals.yaml")
+	require.NoError(t, err)
+	require.Len(t, conf.Monitor.Remote, 3)
+	db1, db2, web1 := conf.Monitor.Remote[0], conf.Monitor.Remote[1], conf.Monitor.Remote[2]
+
+	tests := []struct {
+		name   string
+		target RemoteTarget
+		family MetricFamily
+		want   time.Duration
+	}{
+		{"family from the group", db1, FamilyProcesses, 5 * time.Second},
+		{"top-level interval", db1, FamilySystem, 30 * time.Second},
+		{"top-level family interval", db1, FamilyDisk, 5 * time.Minute},
+		{"target family merged with the group's", db2, FamilyProcesses, 5 * time.Second},
+		{"target family", db2, FamilyDisk, time.Minute},
+		{"target interval over the group's", web1, FamilySystem, 15 * time.Second},
+		{"target interval over top-level families", web1, FamilyDisk, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, conf.IntervalFor(tt.target, tt.family))
+		})
+	}
+
+	assert.Equal(t, 10*time.Second, conf.LocalIntervalFor(FamilyProcesses))
+	assert.Equal(t, 5*time.Minute, conf.LocalIntervalFor(FamilyDisk))
+
+	assert.Equal(t, 2*time.Second, conf.JitterFor(time.Minute))
+	assert.Equal(t, time.Second, conf.JitterFor(time.Second), "capped at the interval")
+	conf.Jitter = ""
+	assert.Equal(t, 6*time.Second, conf.JitterFor(time.Minute), "a tenth of the interval by default")
+	conf.Jitter = "0s"
+	assert.Zero(t, conf.JitterFor(time.Minute))
+}
+
+func TestValidateIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  string
+		intervals map[string]string
+		wantErr   string
+	}{
+		{"unset", "", nil, ""},
+		{"valid", "10s", map[string]string{"processes": "5s", "disk": "5m"}, ""},
+		{"bad format", "often", nil, "monitor.remote[0].interval: invalid interval format"},
+		{"too short", "500ms", nil, "interval must be between 1s and 24h0m0s"},
+		{"unknown family", "", map[string]string{"network": "5s"}, `monitor.remote[0].intervals.network: unknown metric family "network"`},
+		{"bad family interval", "", map[string]string{"disk": "48h"}, "monitor.remote[0].intervals.disk: interval must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIntervals(tt.interval, tt.intervals, "monitor.remote[0]")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	assert.NoError(t, validateJitter(""))
+	assert.NoError(t, validateJitter("0s"))
+	assert.ErrorContains(t, validateJitter("-1s"), "jitter must be between")
+	assert.ErrorContains(t, validateJitter("soon"), "invalid jitter format")
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/inventory"
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// InventoryConfig lists external host inventories that expand into remote targets.
+type InventoryConfig struct {
+	SSHConfig []SSHConfigInventory `mapstructure:"ssh_config"`
+	Ansible   []AnsibleInventory   `mapstructure:"ansible"`
+}
+
+// SSHConfigInventory selects hosts from an OpenSSH client config file.
+// User, Port and SSHKey fill in values the ssh_config does not set.
+type SSHConfigInventory struct {
+	Path           string              `mapstructure:"path"`
+	Hosts          []string            `mapstructure:"hosts"` // Host patterns, e.g. "web-*"
+	User           string              `mapstructure:"user"`
+	Port           int                 `mapstructure:"port"`
+	SSHKey         string              `mapstructure:"ssh_key"`
+	Group          string              `mapstructure:"group"` // config group the hosts join
+	ProcessFilters ProcessFilterConfig `mapstructure:"process_filters"`
+}
+
+// AnsibleInventory selects hosts from an Ansible INI or YAML inventory.
+// GroupFilters gives default process filters per Ansible group; hosts in no
+// listed group fall back to ProcessFilters.
+type AnsibleInventory struct {
+	Path           string                         `mapstructure:"path"`
+	Groups         []string                       `mapstructure:"groups"`
+	User           string                         `mapstructure:"user"`
+	Port           int                            `mapstructure:"port"`
+	SSHKey         string                         `mapstructure:"ssh_key"`
+	Group          string                         `mapstructure:"group"` // config group the hosts join
+	ProcessFilters ProcessFilterConfig            `mapstructure:"process_filters"`
+	GroupFilters   map[string]ProcessFilterConfig `mapstructure:"group_filters"`
+}
+
+// expandInventory resolves every inventory source and appends the resulting
+// hosts to config.Monitor.Remote. Hosts already listed explicitly are skipped;
+// a port left unset by both the inventory and the source is treated as 22 for
+// that comparison.
+// Relative inventory paths are resolved against baseDir.
+func expandInventory(config *Config, baseDir string) error {
+	seen := map[string]bool{}
+	for _, t := range config.Monitor.Remote {
+		seen[targetKey(t.Host, t.Port)] = true
+	}
+	add := func(t RemoteTarget) {
+		if key := targetKey(t.Host, t.Port); !seen[key] {
+			seen[key] = true
+			config.Monitor.Remote = append(config.Monitor.Remote, t)
+		}
+	}
+
+	for i, src := range config.Inventory.SSHConfig {
+		if err := validation.FilePath(src.Path); err != nil {
+			return fmt.Errorf("inventory.ssh_config[%d]: invalid path: %w", i, err)
+		}
+		sshCfg, err := inventory.LoadSSHConfig(resolveConfigPath(src.Path, baseDir))
+		if err != nil {
+			return fmt.Errorf("inventory.ssh_config[%d]: %w", i, err)
+		}
+		hosts, err := sshCfg.Hosts(src.Hosts)
+		if err != nil {
+			return fmt.Errorf("inventory.ssh_config[%d]: %w", i, err)
+		}
+		for _, h := range hosts {
+			t := inventoryTarget(h, src.User, src.Port, src.SSHKey, src.ProcessFilters)
+			jumps, err := resolveJumpChain(sshCfg, h.ProxyJump, map[string]bool{h.Name: true})
+			if err != nil {
+				return fmt.Errorf("inventory.ssh_config[%d]: host %s: %w", i, h.Name, err)
+			}
+			t.ProxyJump, t.JumpHosts = "", jumps
+			t.Group = src.Group
+			add(t)
+		}
+	}
+
+	for i, src := range config.Inventory.Ansible {
+		if err := validation.FilePath(src.Path); err != nil {
+			return fmt.Errorf("inventory.ansible[%d]: invalid path: %w", i, err)
+		}
+		inv, err := inventory.LoadAnsible(resolveConfigPath(src.Path, baseDir))
+		if err != nil {
+			return fmt.Errorf("inventory.ansible[%d]: %w", i, err)
+		}
+		hosts, err := inv.Hosts(src.Groups)
+		if err != nil {
+			return fmt.Errorf("inventory.ansible[%d]: %w", i, err)
+		}
+		for _, h := range hosts {
+			filters := src.ProcessFilters
+			if gf, ok := groupFilters(h.Groups, src.GroupFilters); ok {
+				filters = gf
+			}
+			t := inventoryTarget(h, src.User, src.Port, src.SSHKey, filters)
+			t.Group = src.Group
+			// Ansible groups become tags so output can be filtered by them
+			t.Tags = append(t.Tags, h.Groups...)
+			add(t)
+		}
+	}
+
+	return nil
+}
+
+// inventoryTarget converts an inventory host to a RemoteTarget, using the
+// source-level settings for anything the inventory leaves unset.
+func inventoryTarget(h inventory.Host, user string, port int, sshKey string, filters ProcessFilterConfig) RemoteTarget {
+	t := RemoteTarget{
+		Host:           h.HostName,
+		User:           h.User,
+		Port:           h.Port,
+		SSHKey:         h.IdentityFile,
+		ProxyJump:      h.ProxyJump,
+		ProcessFilters: filters,
+	}
+	if t.User == "" {
+		t.User = user
+	}
+	if t.Port == 0 {
+		t.Port = port
+	}
+	if t.SSHKey == "" {
+		t.SSHKey = sshKey
+	}
+	return t
+}
+
+// groupFilters merges the filters configured for every group the host is in.
+func groupFilters(groups []string, byGroup map[string]ProcessFilterConfig) (ProcessFilterConfig, bool) {
+	var merged ProcessFilterConfig
+	found := false
+	for _, g := range groups {
+		// viper lower-cases map keys
+		f, ok := byGroup[strings.ToLower(g)]
+		if !ok {
+			continue
+		}
+		found = true
+		merged.Keywords = append(merged.Keywords, f.Keywords...)
+		merged.Users = append(merged.Users, f.Users...)
+		merged.Groups = append(merged.Groups, f.Groups...)
+	}
+	return merged, found
+}
+
+// resolveJumpChain turns a ProxyJump value from an ssh_config into a jump
+// chain, resolving each hop through the same config the way ssh(1) does:
+// HostName, User, Port and IdentityFile come from the hop's own Host block,
+// and a hop with its own ProxyJump is reached through that chain first.
+// visiting guards against ProxyJump loops.
+func resolveJumpChain(sshCfg *inventory.SSHConfig, spec string, visiting map[string]bool) ([]JumpConfig, error) {
+	hops, err := ParseProxyJump(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []JumpConfig
+	for _, hop := range hops {
+		if visiting[hop.Host] {
+			return nil, fmt.Errorf("proxy jump loop through %s", hop.Host)
+		}
+		resolved, err := sshCfg.Resolve(hop.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		visiting[hop.Host] = true
+		upstream, err := resolveJumpChain(sshCfg, resolved.ProxyJump, visiting)
+		delete(visiting, hop.Host)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, upstream...)
+
+		if hop.User == "" {
+			hop.User = resolved.User
+		}
+		if hop.Port == 0 {
+			hop.Port = resolved.Port
+		}
+		hop.Host = resolved.HostName
+		hop.SSHKeyPath = resolved.IdentityFile
+		chain = append(chain, hop)
+	}
+	return chain, nil
+}
+
+// resolveConfigPath expands a leading ~/ in path and makes a relative path
+// relative to baseDir, the directory of the config file.
+func resolveConfigPath(path, baseDir string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) && baseDir != "" {
+		return filepath.Join(baseDir, path)
+	}
+	return path
+}
+
+func targetKey(host string, port int) string {
+	if port == 0 {
+		port = defaultSSHPort
+	}
+	return fmt.Sprintf("%s:%d", strings.ToLower(host), port)
+}
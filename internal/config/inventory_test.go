@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigExpandsInventory(t *testing.T) {
+	conf, err := LoadConfig("../../test_configs/inventory.yaml")
+	require.NoError(t, err)
+
+	hosts := map[string]RemoteTarget{}
+	for _, target := range conf.Monitor.Remote {
+		hosts[target.Host] = target
+	}
+	assert.Len(t, conf.Monitor.Remote, 5)
+
+	// the explicit target wins over the db-1 alias pointing at the same address
+	assert.Equal(t, "admin", hosts["10.0.0.5"].User)
+
+	web := hosts["web-1.example.com"]
+	assert.Equal(t, "deploy", web.User)
+	assert.Equal(t, 22, web.Port)
+	assert.Equal(t, "~/.ssh/id_ed25519", web.SSHKey)
+	assert.Empty(t, web.ProxyJump)
+	assert.Equal(t, []JumpConfig{{Host: "bastion.example.com", User: "ops", Port: 2200, SSHKeyPath: "~/.ssh/ops_key"}}, web.JumpHosts)
+	assert.Equal(t, []string{"nginx"}, web.ProcessFilters.Keywords)
+
+	cache := hosts["10.0.1.10"]
+	assert.Equal(t, "redis", cache.User)
+	assert.Equal(t, []string{"redis"}, cache.ProcessFilters.Keywords)
+
+	mq := hosts["10.0.2.10"]
+	assert.Equal(t, "ops", mq.User)
+	assert.Equal(t, 2222, mq.Port)
+	assert.Equal(t, []string{"default"}, mq.ProcessFilters.Keywords)
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// maxJumpHops bounds the length of a jump host chain.
+const maxJumpHops = 8
+
+// ParseProxyJump parses an OpenSSH style ProxyJump value such as
+// "bastion", "ops@bastion1:2222,bastion2" or "ops@[2001:db8::1]:2222" into
+// a chain of jump hosts, ordered from the first hop to the last.
+func ParseProxyJump(spec string) ([]JumpConfig, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var hops []JumpConfig
+	for _, hop := range strings.Split(spec, ",") {
+		hop = strings.TrimSpace(hop)
+		if hop == "" {
+			return nil, fmt.Errorf("empty hop in proxy jump %q", spec)
+		}
+
+		var jc JumpConfig
+		if user, rest, ok := strings.Cut(hop, "@"); ok {
+			jc.User = user
+			hop = rest
+		}
+		host, port, err := validation.SplitHostPort(hop)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy jump hop: %w", err)
+		}
+		jc.Host, jc.Port = host, port
+		hops = append(hops, jc)
+	}
+	return hops, nil
+}
+
+// JumpChain returns the target's jump hosts, from the first hop to the last,
+// with unset users defaulting to the target's user and unset ports to 22.
+func (t RemoteTarget) JumpChain() ([]JumpConfig, error) {
+	hops := t.JumpHosts
+	if len(hops) == 0 {
+		parsed, err := ParseProxyJump(t.ProxyJump)
+		if err != nil {
+			return nil, err
+		}
+		hops = parsed
+	}
+
+	chain := make([]JumpConfig, len(hops))
+	for i, hop := range hops {
+		if hop.User == "" {
+			hop.User = t.User
+		}
+		if hop.Port == 0 {
+			hop.Port = defaultSSHPort
+		}
+		chain[i] = hop
+	}
+	return chain, nil
+}
+
+// validateJumpChain validates the jump host configuration of the target at path
+func validateJumpChain(target *RemoteTarget, path string) error {
+	var errs ValidationErrors
+	if target.ProxyJump != "" && len(target.JumpHosts) > 0 {
+		errs.addf(path+".jump_hosts", "proxy_jump and jump_hosts cannot both be set")
+		return errs
+	}
+
+	hopPath := func(i int) string {
+		if len(target.JumpHosts) > 0 {
+			return fmt.Sprintf("%s.jump_hosts[%d]", path, i)
+		}
+		return path + ".proxy_jump"
+	}
+
+	chain, err := target.JumpChain()
+	if err != nil {
+		errs.addf(path+".proxy_jump", "invalid proxy jump: %w", err)
+		return errs
+	}
+	if len(chain) > maxJumpHops {
+		errs.addf(hopPath(0), "too many jump hosts (max %d)", maxJumpHops)
+	}
+
+	for i, hop := range chain {
+		if err := validation.Host(hop.Host); err != nil {
+			errs.addf(hopPath(i), "jump host %d: invalid host %q: %w", i, hop.Host, err)
+		}
+		if err := validation.Username(hop.User); err != nil {
+			errs.addf(hopPath(i), "jump host %d: invalid user: %w", i, err)
+		}
+		if hop.Port < 1 || hop.Port > 65535 {
+			errs.addf(hopPath(i), "jump host %d: port must be between 1 and 65535", i)
+		}
+		if hop.SSHKeyPath != "" {
+			if err := validation.FilePath(hop.SSHKeyPath); err != nil {
+				errs.addf(hopPath(i), "jump host %d: invalid SSH key path: %w", i, err)
+			}
+		}
+	}
+	return errs.orNil()
+}
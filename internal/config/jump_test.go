@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	hops, err := ParseProxyJump("ops@bastion1:2222,bastion2")
+	require.NoError(t, err)
+	assert.Equal(t, []JumpConfig{
+		{Host: "bastion1", User: "ops", Port: 2222},
+		{Host: "bastion2"},
+	}, hops)
+
+	hops, err = ParseProxyJump("ops@[2001:db8::1]:2222,[fe80::1%eth0],2001:db8::2")
+	require.NoError(t, err)
+	assert.Equal(t, []JumpConfig{
+		{Host: "2001:db8::1", User: "ops", Port: 2222},
+		{Host: "fe80::1%eth0"},
+		{Host: "2001:db8::2"},
+	}, hops)
+
+	for _, spec := range []string{"bastion1,,bastion2", "bastion:ssh", "bastion:", "[2001:db8::1", "[2001:db8::1]2222", "[bastion]:22"} {
+		_, err = ParseProxyJump(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestJumpChainDefaults(t *testing.T) {
+	target := RemoteTarget{
+		User:      "monitor",
+		JumpHosts: []JumpConfig{{Host: "bastion", SSHKeyPath: "~/.ssh/bastion"}},
+	}
+	chain, err := target.JumpChain()
+	require.NoError(t, err)
+	assert.Equal(t, []JumpConfig{{Host: "bastion", User: "monitor", Port: 22, SSHKeyPath: "~/.ssh/bastion"}}, chain)
+}
+
+func TestValidateJumpChain(t *testing.T) {
+	base := RemoteTarget{Host: "db1", User: "monitor", Port: 22, SSHKey: "~/.ssh/id_rsa"}
+
+	tests := []struct {
+		name    string
+		mutate  func(*RemoteTarget)
+		wantErr bool
+	}{
+		{"no jump", func(*RemoteTarget) {}, false},
+		{"shorthand", func(t *RemoteTarget) { t.ProxyJump = "ops@bastion:2222" }, false},
+		{"full", func(t *RemoteTarget) {
+			t.JumpHosts = []JumpConfig{{Host: "bastion", User: "ops", Port: 2222, SSHKeyPath: "~/.ssh/ops"}}
+		}, false},
+		{"both forms", func(t *RemoteTarget) {
+			t.ProxyJump = "bastion"
+			t.JumpHosts = []JumpConfig{{Host: "bastion"}}
+		}, true},
+		{"bad port", func(t *RemoteTarget) { t.JumpHosts = []JumpConfig{{Host: "bastion", Port: 70000}} }, true},
+		{"bad user", func(t *RemoteTarget) { t.JumpHosts = []JumpConfig{{Host: "bastion", User: "a b"}} }, true},
+		{"bad key", func(t *RemoteTarget) { t.JumpHosts = []JumpConfig{{Host: "bastion", SSHKeyPath: "../key"}} }, true},
+		{"bad host", func(t *RemoteTarget) { t.ProxyJump = "bad_host" }, true},
+		{"IPv6 hop", func(t *RemoteTarget) { t.ProxyJump = "ops@[2001:db8::1]:2222" }, false},
+		{"invalid IPv4 hop", func(t *RemoteTarget) { t.ProxyJump = "ops@999.1.1.1" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := base
+			tt.mutate(&target)
+			err := validateRemoteTarget(&target, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRemoteTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
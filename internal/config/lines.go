@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// annotateLines sets the Line of each error to where its path appears in the
+// YAML file. Paths that are not in the file, such as targets expanded from an
+// inventory, are given the line of their closest ancestor that is.
+func annotateLines(path string, errs ValidationErrors) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return
+	}
+
+	for _, e := range errs {
+		e.Line = lineForPath(doc.Content[0], e.Path)
+	}
+
+	// Report problems in file order; those without a line go last.
+	sort.SliceStable(errs, func(i, j int) bool {
+		li, lj := errs[i].Line, errs[j].Line
+		if li == 0 || lj == 0 {
+			return lj == 0 && li != 0
+		}
+		return li < lj
+	})
+}
+
+// lineForPath resolves a path such as monitor.remote[2].user against root.
+func lineForPath(root *yaml.Node, path string) int {
+	node, line := root, 0
+	for _, seg := range splitPath(path) {
+		next, keyLine := child(node, seg)
+		if next == nil {
+			break
+		}
+		node, line = next, keyLine
+	}
+	return line
+}
+
+// splitPath splits monitor.remote[2].user into monitor, remote, [2], user.
+func splitPath(path string) []string {
+	var segs []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			idx := strings.Index(part, "[")
+			switch {
+			case idx < 0:
+				segs = append(segs, part)
+				part = ""
+			case idx > 0:
+				segs = append(segs, part[:idx])
+				part = part[idx:]
+			default:
+				end := strings.Index(part, "]")
+				if end < 0 {
+					segs = append(segs, part)
+					part = ""
+					continue
+				}
+				segs = append(segs, part[:end+1])
+				part = part[end+1:]
+			}
+		}
+	}
+	return segs
+}
+
+// child returns the node for seg under node and the line it starts on.
+// Mapping keys are matched case-insensitively since viper lower-cases them.
+func child(node *yaml.Node, seg string) (*yaml.Node, int) {
+	if strings.HasPrefix(seg, "[") {
+		i, err := strconv.Atoi(strings.Trim(seg, "[]"))
+		if err != nil || node.Kind != yaml.SequenceNode || i < 0 || i >= len(node.Content) {
+			return nil, 0
+		}
+		return node.Content[i], node.Content[i].Line
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, seg) {
+			return node.Content[i+1], node.Content[i].Line
+		}
+	}
+	return nil, 0
+}
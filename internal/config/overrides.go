@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variables that override config keys. The
+// rest of the name is the key path with dots and list indexes written as
+// underscores, e.g. GOSYSMESH_MONITOR_LOCAL_ENABLED for monitor.local.enabled
+// or GOSYSMESH_MONITOR_REMOTE_0_SSH_KEY for monitor.remote[0].ssh_key.
+const EnvPrefix = "GOSYSMESH_"
+
+// Override replaces the value of one config key, whatever the config file
+// says. Overrides from the environment apply first, then those from --set.
+type Override struct {
+	Path   string // key path, e.g. monitor.remote[0].ssh_key
+	Value  string
+	Source string // where the value came from, e.g. GOSYSMESH_INTERVAL or --set
+}
+
+// flagOverrides holds the --set overrides applied by every LoadConfig.
+var flagOverrides []Override
+
+// SetFlagOverrides parses --set arguments of the form key=value and applies
+// them to every configuration loaded from now on.
+func SetFlagOverrides(sets []string) error {
+	overrides := make([]Override, 0, len(sets))
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set %q: want key=value", set)
+		}
+		path, err := canonicalPath(key)
+		if err != nil {
+			return fmt.Errorf("invalid --set %q: %w", set, err)
+		}
+		overrides = append(overrides, Override{Path: path, Value: value, Source: "--set"})
+	}
+	flagOverrides = overrides
+	return nil
+}
+
+// EnvOverrides returns the overrides set by environ, in the format of
+// os.Environ, sorted by variable name. Variables with EnvPrefix that name
+// no config key are ignored, so the prefix can still be used for e.g.
+// passphrase_env.
+func EnvOverrides(environ []string) []Override {
+	var overrides []Override
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		tokens := strings.Split(strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), "_")
+		segs, ok := resolveEnvKey(reflect.TypeOf(Config{}), tokens)
+		if !ok {
+			continue
+		}
+		overrides = append(overrides, Override{Path: joinSegments(segs), Value: value, Source: name})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Source < overrides[j].Source })
+	return overrides
+}
+
+// applyOverrides sets each override in v, on top of the config file.
+func applyOverrides(v *viper.Viper, overrides []Override) error {
+	var errs ValidationErrors
+	for _, o := range overrides {
+		if err := setKey(v, splitPath(o.Path), o.Value); err != nil {
+			errs = append(errs, &ValidationError{Path: o.Path, Source: o.Source, Err: err})
+		}
+	}
+	return errs.orNil()
+}
+
+// markOverridden points each problem with an overridden value at the
+// override instead of the config file. The last override of a key wins.
+func markOverridden(errs ValidationErrors, overrides []Override) {
+	for _, e := range errs {
+		for _, o := range overrides {
+			if e.Path == o.Path || strings.HasPrefix(e.Path, o.Path+".") || strings.HasPrefix(e.Path, o.Path+"[") {
+				e.Line, e.Source = 0, o.Source
+			}
+		}
+	}
+}
+
+// setKey sets the key at segs to value. Keys inside a list replace the
+// whole list, since viper cannot address list elements.
+func setKey(v *viper.Viper, segs []string, value string) error {
+	i := 0
+	for i < len(segs) && !strings.HasPrefix(segs[i], "[") {
+		i++
+	}
+	if i == len(segs) {
+		v.Set(strings.Join(segs, "."), value)
+		return nil
+	}
+
+	base := strings.Join(segs[:i], ".")
+	updated, err := setNested(v.Get(base), segs[i:], value)
+	if err != nil {
+		return err
+	}
+	v.Set(base, updated)
+	return nil
+}
+
+// setNested returns a copy of node, a decoded YAML value, with value set at segs.
+func setNested(node interface{}, segs []string, value string) (interface{}, error) {
+	if len(segs) == 0 {
+		return value, nil
+	}
+
+	seg, rest := segs[0], segs[1:]
+	if strings.HasPrefix(seg, "[") {
+		list, ok := node.([]interface{})
+		index, _ := strconv.Atoi(strings.Trim(seg, "[]"))
+		if !ok || index >= len(list) {
+			return nil, fmt.Errorf("no element %s in the config file", seg)
+		}
+		elem, err := setNested(list[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		copied := append([]interface{}(nil), list...)
+		copied[index] = elem
+		return copied, nil
+	}
+
+	copied := make(map[string]interface{})
+	if m, ok := node.(map[string]interface{}); ok {
+		for k, v := range m {
+			copied[k] = v
+		}
+	}
+	elem, err := setNested(copied[seg], rest, value)
+	if err != nil {
+		return nil, err
+	}
+	copied[seg] = elem
+	return copied, nil
+}
+
+// canonicalPath checks that key names a config key, written with dots and
+// either [n] or .n for list indexes, and returns it in the form used by
+// validation errors, e.g. monitor.remote[0].ssh_key.
+func canonicalPath(key string) (string, error) {
+	var segs []string
+	for _, seg := range splitPath(strings.ToLower(strings.TrimSpace(key))) {
+		if _, err := strconv.Atoi(seg); err == nil {
+			seg = "[" + seg + "]"
+		}
+		segs = append(segs, seg)
+	}
+	if len(segs) == 0 {
+		return "", fmt.Errorf("empty key")
+	}
+	if err := checkPath(reflect.TypeOf(Config{}), segs); err != nil {
+		return "", err
+	}
+	return joinSegments(segs), nil
+}
+
+// checkPath reports whether segs leads from t to a value that can be set
+// from a string.
+func checkPath(t reflect.Type, segs []string) error {
+	t = derefType(t)
+	if len(segs) == 0 {
+		if !isLeaf(t) {
+			return fmt.Errorf("key does not name a single value")
+		}
+		return nil
+	}
+
+	seg := segs[0]
+	switch t.Kind() {
+	case reflect.Struct:
+		if field, ok := keyFields(t)[seg]; ok {
+			return checkPath(field.Type, segs[1:])
+		}
+		return fmt.Errorf("unknown key %q", seg)
+	case reflect.Slice:
+		index, err := strconv.Atoi(strings.Trim(seg, "[]"))
+		if !strings.HasPrefix(seg, "[") || err != nil || index < 0 {
+			return fmt.Errorf("expected a list index, got %q", seg)
+		}
+		return checkPath(t.Elem(), segs[1:])
+	case reflect.Map:
+		if strings.HasPrefix(seg, "[") {
+			return fmt.Errorf("expected a key, got %q", seg)
+		}
+		return checkPath(t.Elem(), segs[1:])
+	}
+	return fmt.Errorf("unknown key %q", seg)
+}
+
+// resolveEnvKey maps the underscore separated tokens of an environment
+// variable name to the key path they name under t. Since key names contain
+// underscores themselves, each way of grouping the tokens is tried until one
+// names a key.
+func resolveEnvKey(t reflect.Type, tokens []string) ([]string, bool) {
+	t = derefType(t)
+	if len(tokens) == 0 {
+		return nil, isLeaf(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := keyFields(t)
+		// Longer names first, so the result does not depend on map order
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if len(names[i]) != len(names[j]) {
+				return len(names[i]) > len(names[j])
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			field := fields[name]
+			parts := strings.Split(name, "_")
+			if len(parts) > len(tokens) || strings.Join(tokens[:len(parts)], "_") != name {
+				continue
+			}
+			if rest, ok := resolveEnvKey(field.Type, tokens[len(parts):]); ok {
+				return append([]string{name}, rest...), true
+			}
+		}
+	case reflect.Slice:
+		if isLeaf(t) {
+			return nil, false
+		}
+		if index, err := strconv.Atoi(tokens[0]); err == nil && index >= 0 {
+			if rest, ok := resolveEnvKey(t.Elem(), tokens[1:]); ok {
+				return append([]string{"[" + tokens[0] + "]"}, rest...), true
+			}
+		}
+	case reflect.Map:
+		for n := 1; n <= len(tokens); n++ {
+			if rest, ok := resolveEnvKey(t.Elem(), tokens[n:]); ok {
+				return append([]string{strings.Join(tokens[:n], "_")}, rest...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// keyFields returns the fields of struct type t by config key name.
+func keyFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// isLeaf reports whether values of t are set from a single string. Lists of
+// scalars are, as comma separated values.
+func isLeaf(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return false
+	case reflect.Slice:
+		return derefType(t.Elem()).Kind() != reflect.Struct
+	}
+	return true
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// joinSegments joins path segments, writing list indexes without a dot.
+func joinSegments(segs []string) string {
+	var b strings.Builder
+	for i, seg := range segs {
+		if i > 0 && !strings.HasPrefix(seg, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// overrides returns the overrides applied by LoadConfig, those from the
+// environment first so that --set wins.
+func overrides() []Override {
+	return append(EnvOverrides(os.Environ()), flagOverrides...)
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setFlags applies --set arguments for the duration of the test.
+func setFlags(t *testing.T, sets ...string) {
+	t.Helper()
+	require.NoError(t, SetFlagOverrides(sets))
+	t.Cleanup(func() { flagOverrides = nil })
+}
+
+func TestLoadConfigOverridePrecedence(t *testing.T) {
+	const path = "../../test_configs/intervals.yaml"
+
+	t.Run("file", func(t *testing.T) {
+		conf, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "30s", conf.Interval)
+	})
+
+	t.Run("env over file", func(t *testing.T) {
+		t.Setenv("GOSYSMESH_INTERVAL", "10s")
+		conf, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "10s", conf.Interval)
+	})
+
+	t.Run("flag over env", func(t *testing.T) {
+		t.Setenv("GOSYSMESH_INTERVAL", "10s")
+		setFlags(t, "interval=5s")
+		conf, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "5s", conf.Interval)
+	})
+
+	t.Run("nested keys", func(t *testing.T) {
+		t.Setenv("GOSYSMESH_MONITOR_LOCAL_ENABLED", "false")
+		t.Setenv("GOSYSMESH_MONITOR_REMOTE_0_SSH_KEY", "~/.ssh/ci_env")
+		t.Setenv("GOSYSMESH_GROUPS_DB_INTERVALS_PROCESSES", "7s")
+		setFlags(t, "monitor.remote[1].ssh_key=~/.ssh/ci_flag", "monitor.remote.2.tags=canary,eu")
+		conf, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.False(t, conf.Monitor.Local.Enabled)
+		assert.Equal(t, "~/.ssh/ci_env", conf.Monitor.Remote[0].SSHKey)
+		assert.Equal(t, "~/.ssh/ci_flag", conf.Monitor.Remote[1].SSHKey)
+		assert.Equal(t, []string{"canary", "eu"}, conf.Monitor.Remote[2].Tags)
+		assert.Equal(t, "7s", conf.Monitor.Remote[0].Intervals["processes"], "groups are inherited after overrides")
+		assert.Equal(t, "db2.example.com", conf.Monitor.Remote[1].Host, "the rest of the element is kept")
+	})
+
+	t.Run("overrides do not outlive a load", func(t *testing.T) {
+		conf, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "~/.ssh/id_ed25519", conf.Monitor.Remote[1].SSHKey)
+	})
+
+	t.Run("problems point at the override", func(t *testing.T) {
+		t.Setenv("GOSYSMESH_JITTER", "soon")
+		setFlags(t, "monitor.remote[0].port=70000", "monitor.remote[9].user=ci")
+		_, err := LoadConfig(path)
+		assert.ErrorContains(t, err, "monitor.remote[9].user (from --set): no element [9]")
+
+		setFlags(t, "monitor.remote[0].port=70000")
+		_, err = LoadConfig(path)
+		var verrs ValidationErrors
+		require.ErrorAs(t, err, &verrs)
+		assert.ElementsMatch(t, []string{
+			"jitter (from GOSYSMESH_JITTER): invalid jitter format: \"soon\"",
+			"monitor.remote[0].port (from --set): port must be between 1 and 65535",
+		}, []string{verrs[0].Error(), verrs[1].Error()})
+	})
+}
+
+func TestEnvOverrides(t *testing.T) {
+	got := EnvOverrides([]string{
+		"HOME=/root",
+		"GOSYSMESH_MONITOR_REMOTE_3_HOST_KEY_POLICY=tofu",
+		"GOSYSMESH_DEFAULTS_SSH_KEY=~/.ssh/ci",
+		"GOSYSMESH_GROUPS_EU_WEST_USER=ops",
+		"GOSYSMESH_INTERVALS_DISK=5m",
+		"GOSYSMESH_KEY_PASSPHRASE=secret",
+		"GOSYSMESH_MONITOR_REMOTE=all",
+	})
+	assert.Equal(t, []Override{
+		{Path: "defaults.ssh_key", Value: "~/.ssh/ci", Source: "GOSYSMESH_DEFAULTS_SSH_KEY"},
+		{Path: "groups.eu_west.user", Value: "ops", Source: "GOSYSMESH_GROUPS_EU_WEST_USER"},
+		{Path: "intervals.disk", Value: "5m", Source: "GOSYSMESH_INTERVALS_DISK"},
+		{Path: "monitor.remote[3].host_key_policy", Value: "tofu", Source: "GOSYSMESH_MONITOR_REMOTE_3_HOST_KEY_POLICY"},
+	}, got)
+}
+
+func TestSetFlagOverrides(t *testing.T) {
+	t.Cleanup(func() { flagOverrides = nil })
+
+	require.NoError(t, SetFlagOverrides([]string{"Monitor.Remote.0.User=ci", "redact.patterns=a=b"}))
+	assert.Equal(t, []Override{
+		{Path: "monitor.remote[0].user", Value: "ci", Source: "--set"},
+		{Path: "redact.patterns", Value: "a=b", Source: "--set"},
+	}, flagOverrides)
+
+	for _, set := range []string{"interval", "intervl=5s", "monitor.remote=x", "monitor.remote.x.user=ci", "tags[0]=a"} {
+		assert.Error(t, SetFlagOverrides([]string{set}), set)
+	}
+}
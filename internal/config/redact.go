@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/redact"
+)
+
+// RedactConfig configures the masking of secrets in process command lines.
+// The built-in patterns always apply.
+type RedactConfig struct {
+	// Patterns are extra regular expressions to mask. A pattern with capture
+	// groups masks only what its groups match.
+	Patterns []string `mapstructure:"patterns"`
+}
+
+// Redactor returns a Redactor for the built-in and configured patterns.
+func (r RedactConfig) Redactor() (*redact.Redactor, error) {
+	return redact.New(r.Patterns)
+}
+
+// validateRedact checks that every pattern compiles.
+func validateRedact(r *RedactConfig) error {
+	var errs ValidationErrors
+	for i, p := range r.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			errs.addf(fmt.Sprintf("redact.patterns[%d]", i), "invalid pattern: %w", err)
+		}
+	}
+	return errs.orNil()
+}
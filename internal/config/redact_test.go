@@ -0,0 +1,20 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRedact(t *testing.T) {
+	assert.NoError(t, validateRedact(&RedactConfig{}))
+	assert.NoError(t, validateRedact(&RedactConfig{Patterns: []string{`sk_live_\w+`, `--license (\S+)`}}))
+
+	err := validateRedact(&RedactConfig{Patterns: []string{`ok`, `(unclosed`}})
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+	require.Len(t, verrs, 1)
+	assert.Equal(t, "redact.patterns[1]", verrs[0].Path)
+}
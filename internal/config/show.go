@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalYAML renders the configuration with the same keys it is read from,
+// in declaration order. Unset values are omitted, except booleans, whose
+// false value is meaningful.
+func (c Config) MarshalYAML() (interface{}, error) {
+	node := toYAMLNode(reflect.ValueOf(c))
+	if node == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return node, nil
+}
+
+// toYAMLNode converts v to a YAML node, returning nil for empty values.
+func toYAMLNode(v reflect.Value) *yaml.Node {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toYAMLNode(v.Elem())
+
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			value := toYAMLNode(v.Field(i))
+			if value == nil {
+				continue
+			}
+			node.Content = append(node.Content, scalarNode(name, "!!str"), value)
+		}
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node
+
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			value := toYAMLNode(v.MapIndex(reflect.ValueOf(k)))
+			if value == nil {
+				value = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+			}
+			node.Content = append(node.Content, scalarNode(k, "!!str"), value)
+		}
+		return node
+
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return nil
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < v.Len(); i++ {
+			item := toYAMLNode(v.Index(i))
+			if item == nil {
+				item = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node
+
+	case reflect.String:
+		if v.String() == "" {
+			return nil
+		}
+		return scalarNode(v.String(), "!!str")
+
+	case reflect.Bool:
+		return scalarNode(fmt.Sprint(v.Bool()), "!!bool")
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return nil
+		}
+		return scalarNode(fmt.Sprint(v.Int()), "!!int")
+
+	case reflect.Float32, reflect.Float64:
+		if v.Float() == 0 {
+			return nil
+		}
+		return scalarNode(fmt.Sprint(v.Float()), "!!float")
+	}
+	return nil
+}
+
+func scalarNode(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of events editors produce for one save.
+const watchDebounce = 250 * time.Millisecond
+
+// Watcher reports changes to a configuration file.
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Watch calls onChange after the file at path is written, replaced or
+// recreated. The parent directory is watched rather than the file itself so
+// that editors which save by renaming a temporary file are handled.
+// onChange runs on the watcher's goroutine and should not block.
+func Watch(path string, onChange func()) (*Watcher, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := fw.Add(filepath.Dir(abs)); err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	w := &Watcher{watcher: fw, done: make(chan struct{})}
+	w.wg.Add(1)
+	go w.run(abs, onChange)
+	return w, nil
+}
+
+func (w *Watcher) run(path string, onChange func()) {
+	defer w.wg.Done()
+
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(watchDebounce, onChange)
+		case _, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
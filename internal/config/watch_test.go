@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gosysmesh.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("interval: \"5s\"\n"), 0o600))
+
+	changed := make(chan struct{}, 1)
+	w, err := Watch(path, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// unrelated files in the same directory are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x"), 0o600))
+	select {
+	case <-changed:
+		t.Fatal("unexpected change notification for another file")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	// editors often save by renaming a temporary file over the original
+	tmp := filepath.Join(dir, ".gosysmesh.yaml.swp")
+	require.NoError(t, os.WriteFile(tmp, []byte("interval: \"10s\"\n"), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notification after the config file was replaced")
+	}
+}
@@ -0,0 +1,453 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleGroup is a group from an Ansible inventory, in either INI or YAML form.
+type ansibleGroup struct {
+	hosts    map[string]map[string]string // host -> host vars
+	vars     map[string]string
+	children []string
+}
+
+// AnsibleInventory is a parsed Ansible inventory.
+type AnsibleInventory struct {
+	groups map[string]*ansibleGroup
+}
+
+// LoadAnsible parses the Ansible inventory at path. Files ending in .yml or
+// .yaml are read as YAML inventories, anything else as INI.
+func LoadAnsible(path string) (*AnsibleInventory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ansible inventory: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return ParseAnsibleYAML(f)
+	default:
+		return ParseAnsibleINI(f)
+	}
+}
+
+func newAnsibleInventory() *AnsibleInventory {
+	return &AnsibleInventory{groups: map[string]*ansibleGroup{}}
+}
+
+func (inv *AnsibleInventory) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{hosts: map[string]map[string]string{}, vars: map[string]string{}}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+func (g *ansibleGroup) addHost(name string, vars map[string]string) {
+	hv, ok := g.hosts[name]
+	if !ok {
+		hv = map[string]string{}
+		g.hosts[name] = hv
+	}
+	for k, v := range vars {
+		hv[k] = v
+	}
+}
+
+// ParseAnsibleINI parses an INI-format Ansible inventory.
+func ParseAnsibleINI(r io.Reader) (*AnsibleInventory, error) {
+	inv := newAnsibleInventory()
+	group, kind := "ungrouped", "hosts"
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := line[1 : len(line)-1]
+			group, kind = section, "hosts"
+			if name, suffix, ok := strings.Cut(section, ":"); ok {
+				group, kind = name, suffix
+			}
+			if kind != "hosts" && kind != "vars" && kind != "children" {
+				return nil, fmt.Errorf("inventory line %d: unknown section type %q", lineNo, kind)
+			}
+			inv.group(group)
+			continue
+		}
+
+		tokens, err := splitINITokens(line)
+		if err != nil {
+			return nil, fmt.Errorf("inventory line %d: %w", lineNo, err)
+		}
+
+		switch kind {
+		case "vars":
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("inventory line %d: expected key=value", lineNo)
+			}
+			inv.group(group).vars[strings.TrimSpace(k)] = unquote(strings.TrimSpace(v))
+		case "children":
+			inv.group(group).children = append(inv.group(group).children, tokens[0])
+			inv.group(tokens[0])
+		default:
+			vars := map[string]string{}
+			for _, tok := range tokens[1:] {
+				k, v, ok := strings.Cut(tok, "=")
+				if !ok {
+					return nil, fmt.Errorf("inventory line %d: expected key=value, got %q", lineNo, tok)
+				}
+				vars[k] = unquote(v)
+			}
+			names, err := expandHostRange(tokens[0])
+			if err != nil {
+				return nil, fmt.Errorf("inventory line %d: %w", lineNo, err)
+			}
+			for _, name := range names {
+				inv.group(group).addHost(name, vars)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// splitINITokens splits a host line on whitespace, keeping quoted values intact.
+func splitINITokens(line string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			cur.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			cur.WriteRune(r)
+		case r == '#' && cur.Len() == 0:
+			return tokens, nil
+		case r == ' ' || r == '\t':
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// expandHostRange expands Ansible host ranges such as web[01:03].example.com
+// or db-[a:c]. Names without a range are returned as-is.
+func expandHostRange(name string) ([]string, error) {
+	open := strings.Index(name, "[")
+	if open < 0 {
+		return []string{name}, nil
+	}
+	end := strings.Index(name[open:], "]")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated host range in %q", name)
+	}
+	end += open
+	prefix, spec, suffix := name[:open], name[open+1:end], name[end+1:]
+
+	from, to, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid host range %q", spec)
+	}
+
+	var items []string
+	if lo, err := strconv.Atoi(from); err == nil {
+		hi, err := strconv.Atoi(to)
+		if err != nil || hi < lo {
+			return nil, fmt.Errorf("invalid host range %q", spec)
+		}
+		for i := lo; i <= hi; i++ {
+			items = append(items, fmt.Sprintf("%0*d", len(from), i))
+		}
+	} else if len(from) == 1 && len(to) == 1 && from[0] <= to[0] {
+		for c := from[0]; c <= to[0]; c++ {
+			items = append(items, string(c))
+		}
+	} else {
+		return nil, fmt.Errorf("invalid host range %q", spec)
+	}
+
+	var names []string
+	for _, item := range items {
+		rest, err := expandHostRange(suffix)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rest {
+			names = append(names, prefix+item+r)
+		}
+	}
+	return names, nil
+}
+
+// yamlGroup mirrors a group in a YAML inventory.
+type yamlGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Children map[string]*yamlGroup             `yaml:"children"`
+}
+
+// ParseAnsibleYAML parses a YAML-format Ansible inventory.
+func ParseAnsibleYAML(r io.Reader) (*AnsibleInventory, error) {
+	var top map[string]*yamlGroup
+	if err := yaml.NewDecoder(r).Decode(&top); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse ansible inventory: %w", err)
+	}
+
+	inv := newAnsibleInventory()
+	for name, g := range top {
+		inv.addYAMLGroup(name, g)
+	}
+	return inv, nil
+}
+
+func (inv *AnsibleInventory) addYAMLGroup(name string, yg *yamlGroup) {
+	g := inv.group(name)
+	if yg == nil {
+		return
+	}
+	for host, vars := range yg.Hosts {
+		g.addHost(host, stringifyVars(vars))
+	}
+	for k, v := range stringifyVars(yg.Vars) {
+		g.vars[k] = v
+	}
+	for child, cg := range yg.Children {
+		g.children = append(g.children, child)
+		inv.addYAMLGroup(child, cg)
+	}
+}
+
+func stringifyVars(in map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		if v != nil {
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+// memberships returns, for every host, the set of groups it belongs to
+// directly or through child groups.
+func (inv *AnsibleInventory) memberships() map[string]map[string]bool {
+	result := map[string]map[string]bool{}
+
+	var collect func(group string, host string, visiting map[string]bool)
+	collect = func(group, host string, visiting map[string]bool) {
+		if visiting[group] {
+			return
+		}
+		visiting[group] = true
+		result[host][group] = true
+		for parent, g := range inv.groups {
+			for _, c := range g.children {
+				if c == group {
+					collect(parent, host, visiting)
+				}
+			}
+		}
+	}
+
+	for name, g := range inv.groups {
+		for host := range g.hosts {
+			if result[host] == nil {
+				result[host] = map[string]bool{"all": true}
+			}
+			collect(name, host, map[string]bool{})
+		}
+	}
+	return result
+}
+
+// depth returns how many parent levels sit above group, so that variables
+// from parent groups can be applied before those of their children.
+func (inv *AnsibleInventory) depth(group string, seen map[string]bool) int {
+	if seen[group] {
+		return 0
+	}
+	seen[group] = true
+	d := 0
+	for parent, g := range inv.groups {
+		for _, c := range g.children {
+			if c == group {
+				if pd := inv.depth(parent, seen) + 1; pd > d {
+					d = pd
+				}
+			}
+		}
+	}
+	delete(seen, group)
+	return d
+}
+
+// Hosts returns every host that belongs to any of groups, directly or via
+// child groups. An empty group list selects every host.
+func (inv *AnsibleInventory) Hosts(groups []string) ([]Host, error) {
+	members := inv.memberships()
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var hosts []Host
+	for _, name := range names {
+		memberOf := members[name]
+		if !selected(memberOf, groups) {
+			continue
+		}
+
+		// Variable precedence: all, then groups from parent to child, then host vars.
+		ordered := make([]string, 0, len(memberOf))
+		for g := range memberOf {
+			if g != "all" {
+				ordered = append(ordered, g)
+			}
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			di, dj := inv.depth(ordered[i], map[string]bool{}), inv.depth(ordered[j], map[string]bool{})
+			if di != dj {
+				return di < dj
+			}
+			return ordered[i] < ordered[j]
+		})
+
+		vars := map[string]string{}
+		for _, g := range append([]string{"all"}, ordered...) {
+			if grp, ok := inv.groups[g]; ok {
+				for k, v := range grp.vars {
+					vars[k] = v
+				}
+			}
+		}
+		for _, g := range ordered {
+			for k, v := range inv.groups[g].hosts[name] {
+				vars[k] = v
+			}
+		}
+		if grp, ok := inv.groups["all"]; ok {
+			for k, v := range grp.hosts[name] {
+				vars[k] = v
+			}
+		}
+
+		host, err := hostFromAnsibleVars(name, vars)
+		if err != nil {
+			return nil, err
+		}
+		host.Groups = ordered
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func selected(memberOf map[string]bool, groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	for _, g := range groups {
+		if memberOf[g] {
+			return true
+		}
+	}
+	return false
+}
+
+// hostFromAnsibleVars maps Ansible connection variables onto a Host.
+func hostFromAnsibleVars(name string, vars map[string]string) (Host, error) {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := vars[k]; ok && v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	host := Host{
+		Name:         name,
+		HostName:     name,
+		User:         first("ansible_user", "ansible_ssh_user"),
+		IdentityFile: first("ansible_ssh_private_key_file", "ansible_private_key_file"),
+		ProxyJump:    proxyJumpFromArgs(first("ansible_ssh_common_args") + " " + first("ansible_ssh_extra_args")),
+	}
+	if addr := first("ansible_host", "ansible_ssh_host"); addr != "" {
+		host.HostName = addr
+	}
+	if p := first("ansible_port", "ansible_ssh_port"); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return Host{}, fmt.Errorf("host %s: invalid ansible_port %q", name, p)
+		}
+		host.Port = port
+	}
+	return host, nil
+}
+
+// proxyJumpFromArgs extracts a jump host from ssh arguments such as
+// "-J bastion" or "-o ProxyJump=bastion".
+func proxyJumpFromArgs(args string) string {
+	fields := strings.Fields(strings.NewReplacer(`"`, " ", `'`, " ").Replace(args))
+	for i, f := range fields {
+		switch {
+		case f == "-J" && i+1 < len(fields):
+			return fields[i+1]
+		case strings.HasPrefix(f, "-J") && len(f) > 2:
+			return f[2:]
+		}
+
+		opt := strings.TrimPrefix(f, "-o")
+		if opt == "" && i+1 < len(fields) && f == "-o" {
+			opt = fields[i+1]
+		}
+		if k, v, ok := strings.Cut(opt, "="); ok && strings.EqualFold(k, "ProxyJump") {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+// Package inventory reads host definitions from existing sources such as
+// OpenSSH client configs and Ansible inventories so they do not have to be
+// duplicated in the gosysmesh config.
+package inventory
+
+import (
+	"path"
+	"strings"
+)
+
+// Host is a single host resolved from an inventory source. Zero values mean
+// the source did not specify the setting.
+type Host struct {
+	Name         string // alias or inventory hostname
+	HostName     string // address to connect to
+	User         string
+	Port         int
+	IdentityFile string
+	ProxyJump    string
+	Groups       []string // groups the host belongs to, including inherited ones
+}
+
+// matchPattern reports whether name matches an ssh_config style pattern,
+// where * matches any sequence and ? matches a single character.
+func matchPattern(pattern, name string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && ok
+}
+
+// matchAny reports whether name matches any of the patterns. An empty pattern
+// list matches everything.
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchPattern(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLiteral reports whether an ssh_config Host pattern names a concrete host.
+func isLiteral(pattern string) bool {
+	return !strings.ContainsAny(pattern, "*?!")
+}
@@ -0,0 +1,158 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleSSHConfig = `
+# global defaults
+User fallback
+
+Host bastion
+    HostName bastion.example.com
+    Port 2222
+
+Host web-1 web-2
+    HostName %h.internal.example.com
+    ProxyJump bastion
+
+Host db1
+    HostName=10.0.0.5
+    User postgres
+    IdentityFile ~/.ssh/db_key
+
+Host web-* !web-2
+    IdentityFile ~/.ssh/web_key
+
+Host *
+    Port 22
+    User ignored
+`
+
+func TestSSHConfigHosts(t *testing.T) {
+	cfg, err := ParseSSHConfig(strings.NewReader(sampleSSHConfig))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"bastion", "web-1", "web-2", "db1"}, cfg.Aliases())
+
+	hosts, err := cfg.Hosts([]string{"web-*", "db1"})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+
+	assert.Equal(t, Host{
+		Name:         "web-1",
+		HostName:     "web-1.internal.example.com",
+		User:         "fallback",
+		Port:         22,
+		IdentityFile: "~/.ssh/web_key",
+		ProxyJump:    "bastion",
+	}, hosts[0])
+
+	// negated pattern excludes web-2 from the IdentityFile block
+	assert.Equal(t, "", hosts[1].IdentityFile)
+
+	assert.Equal(t, "10.0.0.5", hosts[2].HostName)
+	assert.Equal(t, "fallback", hosts[2].User, "first obtained value wins")
+	assert.Equal(t, "~/.ssh/db_key", hosts[2].IdentityFile)
+
+	bastion, err := cfg.Resolve("bastion")
+	require.NoError(t, err)
+	assert.Equal(t, 2222, bastion.Port)
+}
+
+const sampleINI = `
+standalone.example.com
+
+[web]
+web[01:02].example.com ansible_user=deploy
+
+[db]
+db1 ansible_host=10.0.0.5 ansible_port=2222 ansible_ssh_private_key_file=~/.ssh/db_key
+
+[db:vars]
+ansible_user=postgres
+ansible_ssh_common_args='-o ProxyJump=bastion.example.com'
+
+[prod:children]
+web
+db
+
+[prod:vars]
+ansible_user=ops
+`
+
+func TestAnsibleINI(t *testing.T) {
+	inv, err := ParseAnsibleINI(strings.NewReader(sampleINI))
+	require.NoError(t, err)
+
+	hosts, err := inv.Hosts([]string{"prod"})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+
+	assert.Equal(t, Host{
+		Name:         "db1",
+		HostName:     "10.0.0.5",
+		User:         "postgres",
+		Port:         2222,
+		IdentityFile: "~/.ssh/db_key",
+		ProxyJump:    "bastion.example.com",
+		Groups:       []string{"prod", "db"},
+	}, hosts[0])
+
+	assert.Equal(t, "web01.example.com", hosts[1].Name)
+	assert.Equal(t, "deploy", hosts[1].User, "host vars override group vars")
+	assert.Equal(t, "web02.example.com", hosts[2].Name)
+
+	all, err := inv.Hosts(nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+const sampleYAML = `
+all:
+  vars:
+    ansible_user: ops
+  children:
+    db:
+      hosts:
+        db1:
+          ansible_host: 10.0.0.5
+        db2:
+      vars:
+        ansible_port: 2222
+    web:
+      hosts:
+        web1:
+          ansible_ssh_extra_args: "-J bastion"
+`
+
+func TestAnsibleYAML(t *testing.T) {
+	inv, err := ParseAnsibleYAML(strings.NewReader(sampleYAML))
+	require.NoError(t, err)
+
+	hosts, err := inv.Hosts([]string{"db"})
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Equal(t, "10.0.0.5", hosts[0].HostName)
+	assert.Equal(t, "ops", hosts[0].User)
+	assert.Equal(t, 2222, hosts[0].Port)
+	assert.Equal(t, "db2", hosts[1].HostName)
+
+	web, err := inv.Hosts([]string{"web"})
+	require.NoError(t, err)
+	require.Len(t, web, 1)
+	assert.Equal(t, "bastion", web[0].ProxyJump)
+}
+
+func TestExpandHostRange(t *testing.T) {
+	names, err := expandHostRange("node[08:10]-[a:b]")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node08-a", "node08-b", "node09-a", "node09-b", "node10-a", "node10-b"}, names)
+
+	_, err = expandHostRange("node[3:1]")
+	assert.Error(t, err)
+}
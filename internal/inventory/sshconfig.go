@@ -0,0 +1,237 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sshBlock is one Host section of an ssh_config file.
+type sshBlock struct {
+	patterns []string
+	options  map[string]string // lower-cased keyword -> first value
+}
+
+// SSHConfig is a parsed OpenSSH client configuration.
+type SSHConfig struct {
+	blocks []sshBlock
+}
+
+// maxIncludeDepth bounds recursive Include directives.
+const maxIncludeDepth = 8
+
+// LoadSSHConfig parses the ssh_config file at path, following Include directives.
+func LoadSSHConfig(path string) (*SSHConfig, error) {
+	cfg := &SSHConfig{}
+	if err := cfg.parseFile(path, 0, []string{"*"}); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ParseSSHConfig parses an ssh_config from r. Include directives are resolved
+// relative to ~/.ssh.
+func ParseSSHConfig(r io.Reader) (*SSHConfig, error) {
+	cfg := &SSHConfig{}
+	if err := cfg.parse(r, 0, []string{"*"}); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *SSHConfig) parseFile(path string, depth int, patterns []string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open ssh config: %w", err)
+	}
+	defer f.Close()
+
+	return c.parse(f, depth, patterns)
+}
+
+// parse appends the blocks read from r. Options before the first Host line
+// belong to patterns, which is "*" at the top level and the enclosing Host
+// patterns inside an included file.
+func (c *SSHConfig) parse(r io.Reader, depth int, patterns []string) error {
+	c.blocks = append(c.blocks, sshBlock{patterns: patterns, options: map[string]string{}})
+	currentIdx := len(c.blocks) - 1
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := splitSSHOption(line)
+		if value == "" {
+			return fmt.Errorf("ssh config line %d: missing value for %s", lineNo, key)
+		}
+
+		switch key {
+		case "host":
+			c.blocks = append(c.blocks, sshBlock{patterns: strings.Fields(value), options: map[string]string{}})
+			currentIdx = len(c.blocks) - 1
+		case "match":
+			// Match criteria are not evaluated; the block never applies.
+			c.blocks = append(c.blocks, sshBlock{options: map[string]string{}})
+			currentIdx = len(c.blocks) - 1
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("ssh config line %d: Include nested too deeply", lineNo)
+			}
+			if err := c.include(value, depth+1, c.blocks[currentIdx].patterns); err != nil {
+				return err
+			}
+			// Options following an Include keep applying to the enclosing block.
+			c.blocks = append(c.blocks, sshBlock{patterns: c.blocks[currentIdx].patterns, options: map[string]string{}})
+			currentIdx = len(c.blocks) - 1
+		default:
+			opts := c.blocks[currentIdx].options
+			if _, seen := opts[key]; !seen {
+				opts[key] = value
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// include parses every file matching the Include patterns in value.
+func (c *SSHConfig) include(value string, depth int, patterns []string) error {
+	home, _ := os.UserHomeDir()
+	for _, pattern := range strings.Fields(value) {
+		pattern = expandHome(pattern, home)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(home, ".ssh", pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid Include pattern %q: %w", pattern, err)
+		}
+		for _, m := range matches {
+			if err := c.parseFile(m, depth, patterns); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitSSHOption splits a config line into its lower-cased keyword and value,
+// accepting both "Key value" and "Key=value" forms.
+func splitSSHOption(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:idx])
+	value := strings.TrimLeft(line[idx:], " \t")
+	value = strings.TrimPrefix(value, "=")
+	value = strings.TrimSpace(value)
+	return key, strings.Trim(value, `"`)
+}
+
+// applies reports whether the block's Host patterns select name, honouring
+// negated patterns.
+func (b sshBlock) applies(name string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		if strings.HasPrefix(p, "!") {
+			if matchPattern(p[1:], name) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(p, name) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// Aliases returns every concrete Host alias defined in the config, in order.
+func (c *SSHConfig) Aliases() []string {
+	var aliases []string
+	seen := map[string]bool{}
+	for _, b := range c.blocks {
+		for _, p := range b.patterns {
+			if isLiteral(p) && !seen[p] {
+				seen[p] = true
+				aliases = append(aliases, p)
+			}
+		}
+	}
+	return aliases
+}
+
+// Resolve computes the effective settings for name the way ssh(1) does: the
+// first value obtained for each option wins.
+func (c *SSHConfig) Resolve(name string) (Host, error) {
+	opts := map[string]string{}
+	for _, b := range c.blocks {
+		if !b.applies(name) {
+			continue
+		}
+		for k, v := range b.options {
+			if _, seen := opts[k]; !seen {
+				opts[k] = v
+			}
+		}
+	}
+
+	host := Host{
+		Name:         name,
+		HostName:     name,
+		User:         opts["user"],
+		IdentityFile: opts["identityfile"],
+		ProxyJump:    opts["proxyjump"],
+	}
+	if hn, ok := opts["hostname"]; ok {
+		host.HostName = strings.ReplaceAll(hn, "%h", name)
+	}
+	if p, ok := opts["port"]; ok {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return Host{}, fmt.Errorf("host %s: invalid Port %q", name, p)
+		}
+		host.Port = port
+	}
+	if strings.EqualFold(host.ProxyJump, "none") {
+		host.ProxyJump = ""
+	}
+	return host, nil
+}
+
+// Hosts returns the resolved settings for every concrete alias matching any
+// of patterns. An empty pattern list selects every alias.
+func (c *SSHConfig) Hosts(patterns []string) ([]Host, error) {
+	var hosts []Host
+	for _, alias := range c.Aliases() {
+		if !matchAny(patterns, alias) {
+			continue
+		}
+		h, err := c.Resolve(alias)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// expandHome replaces a leading ~ with home.
+func expandHome(p, home string) string {
+	if p == "~" {
+		return home
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(home, p[2:])
+	}
+	return p
+}
@@ -0,0 +1,222 @@
+// Package knownhosts reads and edits OpenSSH known_hosts files and computes
+// host key fingerprints in the format ssh-keygen prints.
+package knownhosts
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Key is a host public key.
+type Key struct {
+	Type string // e.g. ssh-ed25519
+	Blob []byte // the decoded wire-format key
+}
+
+// Fingerprint returns the key's SHA256 fingerprint, e.g. SHA256:abc...
+func (k Key) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// String formats the key as it appears in known_hosts.
+func (k Key) String() string {
+	return k.Type + " " + base64.StdEncoding.EncodeToString(k.Blob)
+}
+
+// Entry is one host key line of a known_hosts file.
+type Entry struct {
+	Line   int    // 1-based line number
+	Marker string // @revoked or @cert-authority, if any
+	Hosts  string // comma-separated patterns, or a hashed |1|salt|hash name
+	Key    Key
+}
+
+// Name returns the name under which OpenSSH records host:port.
+func Name(host string, port int) string {
+	if port == 22 || port == 0 {
+		return host
+	}
+	return "[" + host + "]:" + strconv.Itoa(port)
+}
+
+// Hashed reports whether the entry's host names are hashed.
+func (e Entry) Hashed() bool {
+	return strings.HasPrefix(e.Hosts, "|1|")
+}
+
+// Matches reports whether the entry is for name, as returned by Name.
+// Wildcard and negated patterns are not supported and never match.
+func (e Entry) Matches(name string) bool {
+	if e.Hashed() {
+		parts := strings.Split(e.Hosts, "|")
+		if len(parts) != 4 {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(parts[2])
+		hash, err2 := base64.StdEncoding.DecodeString(parts[3])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(name))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	for _, pattern := range strings.Split(e.Hosts, ",") {
+		if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the host key entries of the known_hosts file at path. A missing
+// file has no entries. Comments and lines that are not host keys are skipped.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if e, ok := parseLine(scanner.Text()); ok {
+			e.Line = n
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	return entries, nil
+}
+
+func parseLine(line string) (Entry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return Entry{}, false
+	}
+	var e Entry
+	if strings.HasPrefix(fields[0], "@") {
+		e.Marker, fields = fields[0], fields[1:]
+	}
+	if len(fields) < 3 {
+		return Entry{}, false
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return Entry{}, false
+	}
+	e.Hosts = fields[0]
+	e.Key = Key{Type: fields[1], Blob: blob}
+	return e, true
+}
+
+// Lookup returns the keys recorded for name, skipping revoked keys and
+// certificate authorities.
+func Lookup(entries []Entry, name string) []Key {
+	var keys []Key
+	for _, e := range entries {
+		if e.Marker == "" && e.Matches(name) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// ParseKeys parses key lines as printed by ssh-keyscan, ignoring comments.
+func ParseKeys(data []byte) []Key {
+	var keys []Key
+	for _, line := range strings.Split(string(data), "\n") {
+		if e, ok := parseLine(line); ok && e.Marker == "" {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// Add appends keys for name to the known_hosts file at path, creating it
+// and its directory if needed.
+func Add(path, name string, keys []Key) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %s\n", name, k)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes every line recording a key for name from the known_hosts
+// file at path and returns how many were removed. Lines listing other names
+// alongside name are removed too.
+func Remove(path, name string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var kept []string
+	removed := 0
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, line := range lines {
+		if e, ok := parseLine(line); ok && e.Matches(name) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// Replace the file atomically so a concurrent reader never sees it
+	// half-written
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".known_hosts-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strings.Join(kept, "")); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,108 @@
+package knownhosts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ed25519Line = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK8BKiG1djmT7q58qX6ECU+njqNWWpp37z/0M18qKXkF"
+	ed25519FP   = "SHA256:nuomRbeBRLVUS8wBufqOZzQSFcMycPnzT0R4iVELpBI"
+	ecdsaLine   = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ/fH9qBa8BZ/P7zMyM6vQT2idKH0BevUuMzRtGrmM8J6USIP6M+bnB1KuIO+UiZbAcluq+Yw1KuaJiOkiyWJ1w="
+	ecdsaFP     = "SHA256:2ReqB4DflTdK9k69m/qZVie+EYts0thcNDa7dtA8U74"
+)
+
+// knownHosts has a plain entry, entries hashed by ssh-keygen -H for
+// db1.example.com and [db2.example.com]:2222, and lines to skip.
+const knownHosts = `# comment
+web1.example.com,10.0.0.1 ` + ed25519Line + ` root@web1
+|1|zC0xoDKwruikPf4ZnaPbD5lm7Kw=|WKZQ3GwmRRuE5/g2wRWMf7cWhpw= ` + ed25519Line + `
+|1|uGg90uqqTu9uYthQ17kUmEfbzYE=|55T1O9K5UN4aNJyekjeY01QeOHk= ` + ecdsaLine + `
+@revoked web2.example.com ` + ecdsaLine + `
+not a key line
+
+`
+
+func writeKnownHosts(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(knownHosts), 0o600))
+	return path
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "db1", Name("db1", 22))
+	assert.Equal(t, "[db1]:2222", Name("db1", 2222))
+}
+
+func TestFingerprintMatchesSSHKeygen(t *testing.T) {
+	keys := ParseKeys([]byte("# db1:22 SSH-2.0-OpenSSH_9.2\ndb1 " + ed25519Line + "\ndb1 " + ecdsaLine + "\n"))
+	require.Len(t, keys, 2)
+	assert.Equal(t, ed25519FP, keys[0].Fingerprint())
+	assert.Equal(t, ecdsaFP, keys[1].Fingerprint())
+	assert.Equal(t, ed25519Line, keys[0].String())
+}
+
+func TestLoadAndLookup(t *testing.T) {
+	path := writeKnownHosts(t)
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, 2, entries[0].Line)
+	assert.True(t, entries[1].Hashed())
+
+	fps := func(keys []Key) []string {
+		var out []string
+		for _, k := range keys {
+			out = append(out, k.Fingerprint())
+		}
+		return out
+	}
+	assert.Equal(t, []string{ed25519FP}, fps(Lookup(entries, "web1.example.com")))
+	assert.Equal(t, []string{ed25519FP}, fps(Lookup(entries, "10.0.0.1")))
+	assert.Equal(t, []string{ed25519FP}, fps(Lookup(entries, "db1.example.com")), "hashed entry")
+	assert.Equal(t, []string{ecdsaFP}, fps(Lookup(entries, "[db2.example.com]:2222")), "hashed entry with a port")
+	assert.Empty(t, Lookup(entries, "db2.example.com"), "the port is part of the name")
+	assert.Empty(t, Lookup(entries, "web2.example.com"), "revoked keys are not trusted")
+
+	entries, err = Load(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAddAndRemove(t *testing.T) {
+	path := writeKnownHosts(t)
+	keys := ParseKeys([]byte("x " + ecdsaLine))
+	require.NoError(t, Add(path, "[db3.example.com]:2200", keys))
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, Lookup(entries, "[db3.example.com]:2200"), 1)
+
+	n, err := Remove(path, "db1.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = Remove(path, "[db3.example.com]:2200")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = Remove(path, "nowhere.example.com")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	entries, err = Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, Lookup(entries, "db1.example.com"))
+	assert.Len(t, Lookup(entries, "web1.example.com"), 1)
+	assert.Len(t, Lookup(entries, "[db2.example.com]:2222"), 1)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# comment\n", "other lines are kept")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,94 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/agent"
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// runSSHInput executes remote commands that read stdin; tests replace it.
+var runSSHInput = RunSSHCommandOpenSSHInput
+
+// errAgentUnavailable marks agent failures after which the target is scraped
+// instead: the binary is missing, cannot run, or speaks another protocol.
+var errAgentUnavailable = errors.New("agent unavailable")
+
+// collectViaAgent collects from target by exchanging a hello and a collect
+// request with the agent in a single SSH invocation.
+func collectViaAgent(target config.RemoteTarget) (*RemoteMetrics, error) {
+	var in bytes.Buffer
+	if err := agent.WriteFrame(&in, agent.Request{Method: agent.MethodHello}); err != nil {
+		return nil, err
+	}
+	if err := agent.WriteFrame(&in, agent.Request{Method: agent.MethodCollect, Filters: target.ProcessFilters}); err != nil {
+		return nil, err
+	}
+
+	clock := clockSample{Sent: time.Now()}
+	output, err := runSSHInput(target, &in, CommandAgent, target.AgentBinary())
+	if err != nil {
+		if IsClass(err, ClassCommand) {
+			return nil, fmt.Errorf("%w on %s: %v", errAgentUnavailable, target.Host, err)
+		}
+		return nil, fmt.Errorf("failed to run agent on %s: %w", target.Host, err)
+	}
+	clock.Received = time.Now()
+
+	out := bufio.NewReader(strings.NewReader(output))
+	var hello agent.Response
+	if err := agent.ReadFrame(out, &hello); err != nil {
+		return nil, fmt.Errorf("%w on %s: no handshake: %v", errAgentUnavailable, target.Host, err)
+	}
+	if hello.Protocol != agent.ProtocolVersion {
+		return nil, fmt.Errorf("%w on %s: agent speaks protocol %d, want %d",
+			errAgentUnavailable, target.Host, hello.Protocol, agent.ProtocolVersion)
+	}
+
+	var resp agent.Response
+	if err := agent.ReadFrame(out, &resp); err != nil {
+		return nil, &Error{Class: ClassParse, Host: target.Host, Err: err}
+	}
+	if resp.Error != "" {
+		return nil, &Error{Class: ClassCommand, Host: target.Host, Stderr: resp.Error, Err: errors.New("agent request failed")}
+	}
+
+	metrics := &RemoteMetrics{Host: target.Host, RoundTrip: clock.RoundTrip(), Families: config.MetricFamilies, Agent: true}
+
+	if resp.ProcessError != "" {
+		metrics.ProcessErr = &Error{Class: ClassCommand, Host: target.Host, Stderr: resp.ProcessError, Err: errors.New("agent failed to list processes")}
+	} else {
+		// Start times are re-anchored on the local clock, as for scraped
+		// processes, using the age the agent measured on its own clock.
+		ref := clock.Sent.Add(clock.RoundTrip() / 2)
+		for _, p := range resp.Processes {
+			p.StartTime = ref.Add(-p.Age)
+			// The agent only knows the built-in patterns
+			p.Cmdline = collector.RedactCmdline(p.Cmdline)
+			metrics.Processes = append(metrics.Processes, p)
+		}
+	}
+
+	if resp.SystemError != "" || resp.System == nil {
+		metrics.SystemErr = &Error{Class: ClassCommand, Host: target.Host, Stderr: resp.SystemError, Err: errors.New("agent failed to collect system stats")}
+		metrics.DiskErr = metrics.SystemErr
+	} else {
+		clock.Remote = resp.Time
+		resp.System.Timestamp = clock.Received
+		metrics.SystemStats = resp.System
+		metrics.RemoteTime = resp.Time
+		metrics.ClockSkew = clock.Skew()
+	}
+
+	if metrics.ProcessErr != nil && metrics.SystemErr != nil {
+		return nil, errors.Join(metrics.ProcessErr, metrics.SystemErr)
+	}
+	metrics.Timestamp = time.Now()
+	return metrics, nil
+}
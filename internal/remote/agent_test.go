@@ -0,0 +1,110 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/agent"
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent replaces runSSHInput for the duration of a test with an agent
+// serving c, or with respond if c is nil.
+func fakeAgent(t *testing.T, c *agent.Collectors, respond func() (string, error)) {
+	t.Helper()
+	orig := runSSHInput
+	t.Cleanup(func() { runSSHInput = orig })
+	runSSHInput = func(target config.RemoteTarget, stdin io.Reader, id CommandID, args ...string) (string, error) {
+		assert.Equal(t, CommandAgent, id)
+		assert.Equal(t, []string{target.AgentBinary()}, args)
+		if c == nil {
+			return respond()
+		}
+		var out bytes.Buffer
+		require.NoError(t, agent.Serve(stdin, &out, *c))
+		return out.String(), nil
+	}
+}
+
+func TestCollectViaAgent(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:           "db1",
+		User:           "postgres",
+		Mode:           config.ModeAgent,
+		ProcessFilters: config.ProcessFilterConfig{Keywords: []string{"postgres"}},
+	}
+
+	t.Run("agent answers", func(t *testing.T) {
+		fakeAgent(t, &agent.Collectors{
+			SystemStats: func() (*collector.SystemStats, error) {
+				return &collector.SystemStats{CPUPercent: 42}, nil
+			},
+			Processes: func(filters config.ProcessFilterConfig) ([]collector.MonitoredProcess, error) {
+				assert.Equal(t, target.ProcessFilters, filters)
+				return []collector.MonitoredProcess{{PID: 812, Name: "postgres", Age: time.Hour}}, nil
+			},
+		}, nil)
+		fakeSSH(t, func() (string, error) {
+			t.Fatal("agent mode must not scrape when the agent answers")
+			return "", nil
+		})
+
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.True(t, m.Agent)
+		assert.NoError(t, m.AgentFallback)
+		assert.Equal(t, 42.0, m.SystemStats.CPUPercent)
+		require.Len(t, m.Processes, 1)
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), m.Processes[0].StartTime, time.Minute)
+		assert.False(t, m.RemoteTime.IsZero())
+	})
+
+	t.Run("binary missing falls back to scraping", func(t *testing.T) {
+		fakeAgent(t, nil, func() (string, error) {
+			return "", &Error{Class: ClassCommand, Host: "db1", ExitStatus: 127, Stderr: "sh: 1: .gosysmesh/bin/gosysmesh: not found", Err: errors.New("exit status 127")}
+		})
+		fakeSSH(t, func() (string, error) {
+			return scriptOutput("812 postgres 1.5 3.2 Ss 100 postgres", "", 0, "1741608000 12.5 2048 8192 45.2 100.0", "", 0), nil
+		})
+
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.False(t, m.Agent)
+		assert.ErrorIs(t, m.AgentFallback, errAgentUnavailable)
+		assert.Len(t, m.Processes, 1)
+	})
+
+	t.Run("protocol mismatch falls back to scraping", func(t *testing.T) {
+		fakeAgent(t, nil, func() (string, error) {
+			var out bytes.Buffer
+			require.NoError(t, agent.WriteFrame(&out, agent.Response{Protocol: agent.ProtocolVersion + 1}))
+			return out.String(), nil
+		})
+		fakeSSH(t, func() (string, error) {
+			return scriptOutput("", "", 0, "1741608000 12.5 2048 8192 45.2 100.0", "", 0), nil
+		})
+
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.ErrorIs(t, m.AgentFallback, errAgentUnavailable)
+	})
+
+	t.Run("unreachable host does not fall back", func(t *testing.T) {
+		fakeAgent(t, nil, func() (string, error) {
+			return "", &Error{Class: ClassConnection, Host: "db1", Err: errors.New("exit status 255")}
+		})
+		fakeSSH(t, func() (string, error) {
+			t.Fatal("an unreachable host must not be scraped")
+			return "", nil
+		})
+
+		_, err := CollectRemoteStats(target)
+		assert.True(t, IsClass(err, ClassConnection))
+	})
+}
@@ -0,0 +1,79 @@
+package remote
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/audit"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// auditLog receives an entry for every remote command executed, when set.
+var auditLog atomic.Pointer[audit.Logger]
+
+// SetAuditLogger records every remote command executed from now on in l.
+// A nil l disables auditing.
+func SetAuditLogger(l *audit.Logger) {
+	auditLog.Store(l)
+}
+
+// commandRun describes one execution of a remote command for the audit log.
+type commandRun struct {
+	target     config.RemoteTarget
+	id         CommandID
+	command    string
+	start      time.Time
+	duration   time.Duration
+	exitStatus int
+	bytesSent  int64
+	bytesOut   int64
+	err        error
+}
+
+// recordAudit appends run to the audit log, if one is set.
+func recordAudit(run commandRun) error {
+	l := auditLog.Load()
+	if l == nil {
+		return nil
+	}
+
+	entry := audit.Entry{
+		Time:       run.start.UTC(),
+		Host:       run.target.Host,
+		Port:       run.target.Port,
+		User:       run.target.User,
+		CommandID:  string(run.id),
+		Command:    run.command,
+		ExitStatus: run.exitStatus,
+		DurationMS: run.duration.Milliseconds(),
+		BytesSent:  run.bytesSent,
+		BytesOut:   run.bytesOut,
+	}
+	if chain, err := run.target.JumpChain(); err == nil {
+		for _, hop := range chain {
+			entry.JumpHosts = append(entry.JumpHosts, hop.User+"@"+net.JoinHostPort(hop.Host, strconv.Itoa(hop.Port)))
+		}
+	}
+	if run.err != nil {
+		entry.Error = run.err.Error()
+		if class, ok := ClassOf(run.err); ok {
+			entry.ErrorClass = class.String()
+		}
+	}
+	return l.Log(entry)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,136 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/sshagent"
+)
+
+// keyAgent holds the keys gosysmesh decrypted with a configured passphrase,
+// so that ssh can use them without prompting. It is started on first use.
+var (
+	keyAgentMu sync.Mutex
+	keyAgent   *sshagent.Agent
+	unlocked   = map[string]bool{} // key path and certificate already added
+)
+
+// StopKeyAgent stops the in-process agent holding unlocked keys, if one was
+// started, and removes its socket.
+func StopKeyAgent() {
+	keyAgentMu.Lock()
+	defer keyAgentMu.Unlock()
+	if keyAgent != nil {
+		keyAgent.Close()
+		keyAgent = nil
+		unlocked = map[string]bool{}
+	}
+}
+
+// unlockKey adds target's passphrase-protected key to the in-process agent,
+// if it is not there yet, and returns the agent's socket.
+func unlockKey(target config.RemoteTarget) (string, error) {
+	keyPath := expandPath(target.SSHKey)
+	certPath := certificateFor(target)
+
+	keyAgentMu.Lock()
+	defer keyAgentMu.Unlock()
+	if keyAgent == nil {
+		a, err := sshagent.Start()
+		if err != nil {
+			return "", err
+		}
+		keyAgent = a
+	}
+
+	id := keyPath + "\x00" + certPath
+	if !unlocked[id] {
+		passphrase, err := readPassphrase(target)
+		if err != nil {
+			return "", err
+		}
+		if err := keyAgent.AddKey(keyPath, passphrase, certPath); err != nil {
+			return "", err
+		}
+		unlocked[id] = true
+	}
+	return keyAgent.Socket(), nil
+}
+
+// readPassphrase returns the passphrase of target's key, read from its
+// passphrase_file or passphrase_env. Like ssh with private keys, it refuses
+// a passphrase file other users can read.
+func readPassphrase(target config.RemoteTarget) ([]byte, error) {
+	if target.PassphraseEnv != "" {
+		value, ok := os.LookupEnv(target.PassphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("passphrase variable %s is not set", target.PassphraseEnv)
+		}
+		return []byte(value), nil
+	}
+
+	path := expandPath(target.PassphraseFile)
+	if err := checkKeyPermissions(path); err != nil {
+		return nil, fmt.Errorf("passphrase file %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+	return []byte(strings.TrimRight(string(data), "\r\n")), nil
+}
+
+// certificateFor returns the certificate presented with target's key: its
+// certificate setting, or else the key path with -cert.pub appended if that
+// file exists.
+func certificateFor(target config.RemoteTarget) string {
+	if target.Certificate != "" {
+		return expandPath(target.Certificate)
+	}
+	if target.SSHKey == "" {
+		return ""
+	}
+	cert := expandPath(target.SSHKey) + "-cert.pub"
+	if _, err := os.Stat(cert); err != nil {
+		return ""
+	}
+	return cert
+}
+
+// authArgs returns the ssh options selecting how target authenticates.
+// agentSocket, if set, is the agent holding target's unlocked key, which
+// takes precedence over identity_agent.
+func authArgs(target config.RemoteTarget, agentSocket string) []string {
+	var args []string
+	if target.SSHKey != "" {
+		args = append(args, "-i", expandPath(target.SSHKey))
+	}
+	if agentSocket == "" && target.IdentityAgent != "" {
+		agentSocket = expandPath(target.IdentityAgent)
+	}
+	if agentSocket != "" {
+		args = append(args, "-o", "IdentityAgent="+escapeTokens(agentSocket))
+	}
+	if target.Certificate != "" {
+		args = append(args, "-o", "CertificateFile="+escapeTokens(expandPath(target.Certificate)))
+	}
+	return args
+}
+
+// expandPath expands environment variables and a leading ~/ in path.
+func expandPath(path string) string {
+	return expandTilde(os.ExpandEnv(path))
+}
+
+// escapeTokens escapes the % of a path passed as an ssh option that expands
+// %-tokens.
+func escapeTokens(path string) string {
+	return strings.ReplaceAll(path, "%", "%%")
+}
+
+// errNoAgent means a target relies on an ssh-agent but none is available.
+var errNoAgent = errors.New("no SSH key set and no agent available (set ssh_key, identity_agent or SSH_AUTH_SOCK)")
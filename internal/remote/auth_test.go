@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/sshagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeEncryptedKey writes a new ed25519 key encrypted with passphrase.
+func writeEncryptedKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "monitor")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func TestBuildSSHArgsAuth(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	target := config.RemoteTarget{Host: "db1.internal", User: "monitor", Port: 22}
+
+	_, err := buildSSHArgs(target, "uptime")
+	assert.ErrorIs(t, err, errNoAgent)
+
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.NotContains(t, args, "-i", "the agent offers its keys")
+
+	target.IdentityAgent = "/run/user/1000/100%agent.sock"
+	target.Certificate = "/keys/monitor-cert.pub"
+	args, err = buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "IdentityAgent=/run/user/1000/100%%agent.sock")
+	assert.Contains(t, args, "CertificateFile=/keys/monitor-cert.pub")
+}
+
+func TestBuildSSHArgsUnlocksPassphraseKey(t *testing.T) {
+	t.Cleanup(StopKeyAgent)
+	target := config.RemoteTarget{
+		Host: "db1.internal", User: "monitor", Port: 22,
+		SSHKey:        writeEncryptedKey(t, "s3cret"),
+		PassphraseEnv: "GOSYSMESH_TEST_PASSPHRASE",
+	}
+
+	t.Setenv("GOSYSMESH_TEST_PASSPHRASE", "wrong")
+	_, err := buildSSHArgs(target, "uptime")
+	require.Error(t, err)
+	assert.True(t, IsClass(err, ClassAuth))
+	assert.ErrorContains(t, err, "wrong or missing passphrase")
+
+	t.Setenv("GOSYSMESH_TEST_PASSPHRASE", "s3cret")
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	socket := keyAgent.Socket()
+	assert.Contains(t, args, "IdentityAgent="+socket)
+	assert.Contains(t, args, "-i", "ssh offers the agent key matching ssh_key")
+
+	keys, err := sshagent.List(socket)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	// The key is unlocked once
+	_, err = buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	keys, err = sshagent.List(socket)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	StopKeyAgent()
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.pass")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o644))
+	target := config.RemoteTarget{PassphraseFile: path}
+
+	_, err := readPassphrase(target)
+	assert.ErrorContains(t, err, "too open")
+
+	require.NoError(t, os.Chmod(path, 0o600))
+	passphrase, err := readPassphrase(target)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", string(passphrase))
+
+	_, err = readPassphrase(config.RemoteTarget{PassphraseEnv: "GOSYSMESH_TEST_UNSET"})
+	assert.ErrorContains(t, err, "is not set")
+}
+
+func TestCertificateFor(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "monitor")
+	target := config.RemoteTarget{SSHKey: key}
+	assert.Empty(t, certificateFor(target))
+
+	require.NoError(t, os.WriteFile(key+"-cert.pub", nil, 0o644))
+	assert.Equal(t, key+"-cert.pub", certificateFor(target))
+
+	target.Certificate = "/keys/other-cert.pub"
+	assert.Equal(t, "/keys/other-cert.pub", certificateFor(target))
+}
@@ -0,0 +1,310 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// CommandID names a command in the registry of remote commands. Remote
+// hosts are only ever sent commands built from the registry.
+type CommandID string
+
+// Built-in commands.
+const (
+	CommandCollect      CommandID = "collect"
+	CommandPs           CommandID = "ps"
+	CommandStats        CommandID = "stats"
+	CommandProbe        CommandID = "probe"
+	CommandToolsCheck   CommandID = "tools-check"
+	CommandProcRead     CommandID = "proc-read"
+	CommandAgent        CommandID = "agent"
+	CommandAgentVersion CommandID = "agent-version"
+	CommandArch         CommandID = "arch"
+	CommandUpload       CommandID = "deploy-upload"
+	CommandChecksum     CommandID = "deploy-checksum"
+	CommandActivate     CommandID = "deploy-activate"
+	CommandDiscard      CommandID = "deploy-discard"
+	CommandRollback     CommandID = "deploy-rollback"
+	CommandRemove       CommandID = "deploy-remove"
+)
+
+// ParamType is the type of a command parameter. Each type validates values
+// and shell-quotes them, so a built command never contains anything but the
+// template and quoted parameters.
+type ParamType int
+
+const (
+	// ParamUser is a login name.
+	ParamUser ParamType = iota
+	// ParamPath is a file path on the remote host. A leading ~/ is left
+	// unquoted so the remote shell expands it.
+	ParamPath
+	// ParamProcFile is the name of a file directly under /proc, from procFiles.
+	ParamProcFile
+)
+
+func (t ParamType) String() string {
+	switch t {
+	case ParamUser:
+		return "user"
+	case ParamPath:
+		return "path"
+	default:
+		return "proc file"
+	}
+}
+
+// procFiles are the /proc files that CommandProcRead may read.
+var procFiles = []string{"cpuinfo", "loadavg", "meminfo", "stat", "uptime", "version"}
+
+var (
+	remotePathRegex = regexp.MustCompile(`^[a-zA-Z0-9_./~-]+$`)
+	// quotedPatterns match a parameter value as emitted by quote.
+	quotedPatterns = map[ParamType]string{
+		ParamUser:     `'` + validation.UsernameChars + `'`,
+		ParamPath:     `(?:~/)?'[a-zA-Z0-9_./~-]+'`,
+		ParamProcFile: `'[a-z]+'`,
+	}
+)
+
+func (t ParamType) validate(value string) error {
+	switch t {
+	case ParamUser:
+		return validation.Username(value)
+	case ParamPath:
+		if value == "" || !remotePathRegex.MatchString(value) {
+			return fmt.Errorf("invalid path %q", value)
+		}
+		if strings.Contains(value, "..") {
+			return errors.New("path traversal not allowed")
+		}
+		return nil
+	case ParamProcFile:
+		for _, f := range procFiles {
+			if value == f {
+				return nil
+			}
+		}
+		return fmt.Errorf("/proc/%s is not readable through the registry", value)
+	}
+	return fmt.Errorf("unknown parameter type %d", t)
+}
+
+func (t ParamType) quote(value string) string {
+	if t == ParamPath && strings.HasPrefix(value, "~/") {
+		return "~/" + shellQuote(value[2:])
+	}
+	return shellQuote(value)
+}
+
+// unquote reverses quote for a value matched by quotedPatterns.
+func (t ParamType) unquote(quoted string) string {
+	prefix := ""
+	if t == ParamPath && strings.HasPrefix(quoted, "~/") {
+		prefix, quoted = "~/", quoted[2:]
+	}
+	return prefix + strings.Trim(quoted, "'")
+}
+
+// Param is a named, typed parameter of a command template, written as
+// {{name}} in the template.
+type Param struct {
+	Name string
+	Type ParamType
+}
+
+// Command is a registered remote command.
+type Command struct {
+	ID CommandID
+	// Template is the shell command with {{name}} placeholders for Params.
+	Template string
+	Params   []Param
+	// ReadOnly commands only read host state and never run a program named
+	// by a parameter; they are the only ones restricted-shell accepts.
+	ReadOnly bool
+
+	pattern *regexp.Regexp
+}
+
+// registry holds every command that may be sent to a remote host.
+var registry = map[CommandID]*Command{}
+
+// Register adds a command to the registry, e.g. a custom check. Every
+// placeholder in the template must be declared in Params and vice versa.
+func Register(c Command) error {
+	if c.ID == "" {
+		return errors.New("command ID cannot be empty")
+	}
+	if _, ok := registry[c.ID]; ok {
+		return fmt.Errorf("command %q is already registered", c.ID)
+	}
+	if c.Template == "" {
+		return fmt.Errorf("command %q has an empty template", c.ID)
+	}
+
+	pattern := regexp.QuoteMeta(c.Template)
+	for i, p := range c.Params {
+		placeholder := "{{" + p.Name + "}}"
+		if !strings.Contains(c.Template, placeholder) {
+			return fmt.Errorf("command %q does not use parameter %q", c.ID, p.Name)
+		}
+		quoted, ok := quotedPatterns[p.Type]
+		if !ok {
+			return fmt.Errorf("command %q parameter %q has unknown type", c.ID, p.Name)
+		}
+		// The first occurrence captures the value; MatchCommand checks that
+		// later ones repeat it by rebuilding the command
+		quotedPlaceholder := regexp.QuoteMeta(placeholder)
+		pattern = strings.Replace(pattern, quotedPlaceholder, fmt.Sprintf("(?P<p%d>%s)", i, quoted), 1)
+		pattern = strings.ReplaceAll(pattern, quotedPlaceholder, "(?:"+quoted+")")
+	}
+	if strings.Contains(pattern, regexp.QuoteMeta("{{")) {
+		return fmt.Errorf("command %q has undeclared placeholders", c.ID)
+	}
+
+	c.pattern = regexp.MustCompile("^" + pattern + "$")
+	registry[c.ID] = &c
+	return nil
+}
+
+func mustRegister(c Command) {
+	if err := Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// BuildCommand validates args against the parameters of the registered
+// command id and returns the shell command with each argument quoted.
+func BuildCommand(id CommandID, args ...string) (string, error) {
+	c, ok := registry[id]
+	if !ok {
+		return "", fmt.Errorf("unknown remote command %q", id)
+	}
+	if len(args) != len(c.Params) {
+		return "", fmt.Errorf("command %q takes %d argument(s), got %d", id, len(c.Params), len(args))
+	}
+
+	command := c.Template
+	for i, p := range c.Params {
+		if err := p.Type.validate(args[i]); err != nil {
+			return "", fmt.Errorf("invalid %s for command %q: %w", p.Name, id, err)
+		}
+		command = strings.ReplaceAll(command, "{{"+p.Name+"}}", p.Type.quote(args[i]))
+	}
+	return command, nil
+}
+
+// MatchCommand returns the registered command that builds exactly command,
+// along with its arguments. It is the inverse of BuildCommand, used by
+// restricted-shell to decide whether a requested command is known.
+func MatchCommand(command string) (*Command, []string, bool) {
+	ids := make([]string, 0, len(registry))
+	for id := range registry {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		c := registry[CommandID(id)]
+		m := c.pattern.FindStringSubmatch(command)
+		if m == nil {
+			continue
+		}
+		if args, ok := c.matchArgs(command, m); ok {
+			return c, args, true
+		}
+	}
+	return nil, nil, false
+}
+
+// matchArgs extracts and validates the value captured for each parameter
+// from submatches, and checks that rebuilding the command from them
+// reproduces command exactly.
+func (c *Command) matchArgs(command string, submatches []string) ([]string, bool) {
+	args := make([]string, len(c.Params))
+	for i, p := range c.Params {
+		args[i] = p.Type.unquote(submatches[c.pattern.SubexpIndex(fmt.Sprintf("p%d", i))])
+		if p.Type.validate(args[i]) != nil {
+			return nil, false
+		}
+	}
+	rebuilt, err := BuildCommand(c.ID, args...)
+	return args, err == nil && rebuilt == command
+}
+
+func init() {
+	psTemplate := "ps -u {{user}} -o pid,user,%cpu,%mem,stat,etimes,args --no-headers"
+	// The remote epoch time is printed first so clock skew can be measured in
+	// the same round-trip, but read after top, which can take most of a
+	// second: a reading taken before it would be early for the midpoint of
+	// the round-trip that the skew estimate assumes.
+	systemTemplate := `cpu=$(top -bn1 | grep "Cpu(s)" | awk '{print $2}' | sed 's/%us,//'); date +%s.%N; echo "$cpu"; free -m | awk 'NR==2{printf "%.0f %.0f\n", $3,$2}'`
+	diskTemplate := `df -h / | awk 'NR==2{gsub(/[^0-9.]/, "", $3); gsub(/[^0-9.]/, "", $2); printf "%.1f %.1f\n", $3, $2}'`
+	statsTemplate := systemTemplate + "; " + diskTemplate
+	user := []Param{{Name: "user", Type: ParamUser}}
+	binary := []Param{{Name: "path", Type: ParamPath}}
+
+	// Collection
+	mustRegister(Command{ID: CommandPs, Template: psTemplate, Params: user, ReadOnly: true})
+	mustRegister(Command{ID: CommandStats, Template: statsTemplate, ReadOnly: true})
+	mustRegister(Command{
+		ID:       CommandCollect,
+		Template: scriptSection(sectionProcesses, psTemplate) + "; " + scriptSection(sectionStats, statsTemplate),
+		Params:   user,
+		ReadOnly: true,
+	})
+	// Families collected on their own schedule get a script with only
+	// their sections; system stats and disk usage due together share the
+	// stats section.
+	for _, families := range familySubsets() {
+		var sections []string
+		if hasFamily(families, config.FamilyProcesses) {
+			sections = append(sections, scriptSection(sectionProcesses, psTemplate))
+		}
+		switch system, disk := hasFamily(families, config.FamilySystem), hasFamily(families, config.FamilyDisk); {
+		case system && disk:
+			sections = append(sections, scriptSection(sectionStats, statsTemplate))
+		case system:
+			sections = append(sections, scriptSection(sectionSystem, systemTemplate))
+		case disk:
+			sections = append(sections, scriptSection(sectionDisk, diskTemplate))
+		}
+		c := Command{ID: collectCommandID(families), Template: strings.Join(sections, "; "), ReadOnly: true}
+		if hasFamily(families, config.FamilyProcesses) {
+			c.Params = user
+		}
+		mustRegister(c)
+	}
+	mustRegister(Command{ID: CommandProcRead, Template: "cat /proc/{{file}}", Params: []Param{{Name: "file", Type: ParamProcFile}}, ReadOnly: true})
+	mustRegister(Command{ID: CommandAgent, Template: "{{path}} agent --stdio", Params: binary, ReadOnly: true})
+
+	// Diagnostics
+	mustRegister(Command{ID: CommandProbe, Template: "uname -sm", ReadOnly: true})
+	mustRegister(Command{
+		ID:       CommandToolsCheck,
+		Template: "for c in " + strings.Join(requiredTools, " ") + "; do command -v $c >/dev/null 2>&1 || echo missing:$c; done; test -r /proc/stat || echo missing:/proc",
+		ReadOnly: true,
+	})
+
+	// Agent deployment. None of these are read-only: agent-version runs
+	// whatever binary it is given and deploy-checksum reads any file, and
+	// restricted-shell refuses deploy anyway.
+	mustRegister(Command{ID: CommandArch, Template: "uname -m"})
+	mustRegister(Command{ID: CommandAgentVersion, Template: "{{path}} version", Params: binary})
+	mustRegister(Command{
+		ID:       CommandUpload,
+		Template: "mkdir -p {{dir}} && cat > {{path}} && chmod 755 {{path}}",
+		Params:   []Param{{Name: "dir", Type: ParamPath}, {Name: "path", Type: ParamPath}},
+	})
+	mustRegister(Command{ID: CommandChecksum, Template: "sha256sum {{path}} 2>/dev/null || shasum -a 256 {{path}}", Params: binary})
+	mustRegister(Command{ID: CommandActivate, Template: "if [ -f {{path}} ]; then mv -f {{path}} {{path}}.prev; fi && mv -f {{path}}.new {{path}}", Params: binary})
+	mustRegister(Command{ID: CommandDiscard, Template: "rm -f {{path}}.new", Params: binary})
+	mustRegister(Command{ID: CommandRollback, Template: "test -f {{path}}.prev && mv -f {{path}}.prev {{path}}", Params: binary})
+	mustRegister(Command{ID: CommandRemove, Template: "rm -f {{path}} {{path}}.prev {{path}}.new", Params: binary})
+}
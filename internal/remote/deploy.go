@@ -0,0 +1,202 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// DeployAction selects what Deploy does on each target.
+type DeployAction int
+
+const (
+	// DeployInstall uploads the binary, keeping the previous one for rollback.
+	DeployInstall DeployAction = iota
+	// DeployRollback restores the binary that the last install replaced.
+	DeployRollback
+	// DeployRemove deletes the binary and its backup.
+	DeployRemove
+)
+
+func (a DeployAction) String() string {
+	switch a {
+	case DeployInstall:
+		return "install"
+	case DeployRollback:
+		return "rollback"
+	default:
+		return "remove"
+	}
+}
+
+// DeployOptions configures Deploy.
+type DeployOptions struct {
+	Action DeployAction
+	// Version is the version the installed binary must report.
+	Version string
+	// BinaryDir holds prebuilt binaries named gosysmesh-linux-<arch>. When a
+	// binary for the target's architecture is not found there, the running
+	// executable is used if it matches.
+	BinaryDir string
+}
+
+// DeployResult is the outcome of Deploy on one target.
+type DeployResult struct {
+	Host     string
+	Path     string
+	Arch     string // GOARCH of the target, for installs
+	Checksum string // SHA-256 of the installed binary, for installs
+	Version  string // version reported by the binary now in place
+	Err      error
+}
+
+// unameArch maps `uname -m` output to GOARCH.
+var unameArch = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// Deploy installs, rolls back or removes the agent binary at the target's
+// agent path.
+func Deploy(target config.RemoteTarget, opts DeployOptions) DeployResult {
+	result := DeployResult{Host: target.Host, Path: target.AgentBinary()}
+	if !strings.Contains(result.Path, "/") {
+		// A bare name is looked up in PATH when run, not where it was uploaded
+		result.Err = fmt.Errorf("agent_path %q must contain a directory, e.g. %s", result.Path, config.DefaultAgentPath)
+		return result
+	}
+
+	switch opts.Action {
+	case DeployInstall:
+		result.Err = installAgent(target, opts, &result)
+	case DeployRollback:
+		result.Err = rollbackAgent(target, &result)
+	case DeployRemove:
+		if _, err := runSSH(target, CommandRemove, result.Path); err != nil {
+			result.Err = fmt.Errorf("failed to remove agent: %w", err)
+		}
+	}
+	return result
+}
+
+func installAgent(target config.RemoteTarget, opts DeployOptions, result *DeployResult) error {
+	output, err := runSSH(target, CommandArch)
+	if err != nil {
+		return fmt.Errorf("failed to detect architecture: %w", err)
+	}
+	machine := strings.TrimSpace(output)
+	arch, ok := unameArch[machine]
+	if !ok {
+		return fmt.Errorf("unsupported architecture %q", machine)
+	}
+	result.Arch = arch
+
+	binary, err := agentBinaryFor(arch, opts.BinaryDir)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		return fmt.Errorf("failed to read binary: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	staged := result.Path + ".new"
+	if _, err := runSSHInput(target, bytes.NewReader(data), CommandUpload, path.Dir(result.Path), staged); err != nil {
+		return fmt.Errorf("failed to upload binary: %w", err)
+	}
+
+	// Anything wrong with the staged binary leaves the current one in place
+	if err := verifyAgent(target, staged, checksum, opts.Version, result); err != nil {
+		if _, rmErr := runSSH(target, CommandDiscard, result.Path); rmErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to discard staged binary: %w", rmErr))
+		}
+		return err
+	}
+	result.Checksum = checksum
+
+	if _, err := runSSH(target, CommandActivate, result.Path); err != nil {
+		return fmt.Errorf("failed to activate binary: %w", err)
+	}
+	return nil
+}
+
+func rollbackAgent(target config.RemoteTarget, result *DeployResult) error {
+	if _, err := runSSH(target, CommandRollback, result.Path); err != nil {
+		if IsClass(err, ClassCommand) {
+			return errors.New("no previous binary to roll back to")
+		}
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+	output, err := runSSH(target, CommandAgentVersion, result.Path)
+	if err != nil {
+		return fmt.Errorf("restored binary does not run: %w", err)
+	}
+	result.Version = parseVersionOutput(output)
+	return nil
+}
+
+// verifyAgent checks the checksum of the binary at binPath and that it runs
+// and reports version.
+func verifyAgent(target config.RemoteTarget, binPath, checksum, version string, result *DeployResult) error {
+	output, err := runSSH(target, CommandChecksum, binPath)
+	if err != nil {
+		return fmt.Errorf("failed to checksum uploaded binary: %w", err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 || fields[0] != checksum {
+		return errors.New("checksum mismatch: uploaded binary is corrupt")
+	}
+
+	output, err = runSSH(target, CommandAgentVersion, binPath)
+	if err != nil {
+		return fmt.Errorf("uploaded binary does not run: %w", err)
+	}
+	result.Version = parseVersionOutput(output)
+	if version != "" && result.Version != version {
+		return fmt.Errorf("uploaded binary reports version %q, want %q", result.Version, version)
+	}
+	return nil
+}
+
+// parseVersionOutput extracts the version from `gosysmesh version` output.
+func parseVersionOutput(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// agentBinaryFor returns the local binary to deploy to a linux/arch host.
+func agentBinaryFor(arch, dir string) (string, error) {
+	if dir != "" {
+		candidate := filepath.Join(dir, "gosysmesh-linux-"+arch)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	if runtime.GOOS == "linux" && runtime.GOARCH == arch {
+		return os.Executable()
+	}
+	return "", fmt.Errorf("no binary for linux/%s: build gosysmesh-linux-%s into the binary directory", arch, arch)
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shellHost replaces runSSH and runSSHInput with a local sh running in home,
+// standing in for a remote aarch64 host.
+func shellHost(t *testing.T, home string) {
+	t.Helper()
+	origSSH, origInput := runSSH, runSSHInput
+	t.Cleanup(func() { runSSH, runSSHInput = origSSH, origInput })
+
+	runSSHInput = func(target config.RemoteTarget, stdin io.Reader, id CommandID, args ...string) (string, error) {
+		if id == CommandArch {
+			return "aarch64\n", nil
+		}
+		command, err := BuildCommand(id, args...)
+		require.NoError(t, err)
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = home
+		cmd.Stdin = stdin
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			require.True(t, errors.As(err, &exitErr), "running %q: %v", command, err)
+			return "", &Error{Class: ClassCommand, Host: target.Host, Stderr: stderr.String(), ExitStatus: exitErr.ExitCode(), Err: err}
+		}
+		return stdout.String(), nil
+	}
+	runSSH = func(target config.RemoteTarget, id CommandID, args ...string) (string, error) {
+		return runSSHInput(target, nil, id, args...)
+	}
+}
+
+// fakeBinary writes a stand-in gosysmesh binary reporting version to dir.
+func fakeBinary(t *testing.T, dir, version string) {
+	t.Helper()
+	script := "#!/bin/sh\necho gosysmesh " + version + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gosysmesh-linux-arm64"), []byte(script), 0o755))
+}
+
+func TestDeploy(t *testing.T) {
+	home := t.TempDir()
+	binDir := t.TempDir()
+	shellHost(t, home)
+	target := config.RemoteTarget{Host: "db1"}
+	installed := filepath.Join(home, config.DefaultAgentPath)
+
+	fakeBinary(t, binDir, "v1.0.0")
+	r := Deploy(target, DeployOptions{Action: DeployInstall, Version: "v1.0.0", BinaryDir: binDir})
+	require.NoError(t, r.Err)
+	assert.Equal(t, "arm64", r.Arch)
+	assert.Equal(t, "v1.0.0", r.Version)
+	assert.Len(t, r.Checksum, 64)
+	assert.FileExists(t, installed)
+	assert.NoFileExists(t, installed+".new")
+
+	fakeBinary(t, binDir, "v1.1.0")
+	r = Deploy(target, DeployOptions{Action: DeployInstall, Version: "v1.1.0", BinaryDir: binDir})
+	require.NoError(t, r.Err)
+	assert.FileExists(t, installed+".prev", "the replaced binary is kept for rollback")
+
+	// A binary that reports the wrong version is not activated
+	r = Deploy(target, DeployOptions{Action: DeployInstall, Version: "v2.0.0", BinaryDir: binDir})
+	assert.ErrorContains(t, r.Err, `reports version "v1.1.0"`)
+	assert.NoFileExists(t, installed+".new")
+	out, err := runSSH(target, CommandAgentVersion, config.DefaultAgentPath)
+	require.NoError(t, err)
+	assert.Equal(t, "gosysmesh v1.1.0\n", out)
+
+	r = Deploy(target, DeployOptions{Action: DeployRollback})
+	require.NoError(t, r.Err)
+	assert.Equal(t, "v1.0.0", r.Version)
+
+	r = Deploy(target, DeployOptions{Action: DeployRollback})
+	assert.ErrorContains(t, r.Err, "no previous binary")
+
+	r = Deploy(target, DeployOptions{Action: DeployRemove})
+	require.NoError(t, r.Err)
+	assert.NoFileExists(t, installed)
+}
+
+func TestDeployRejectsUnknownArchAndBarePath(t *testing.T) {
+	shellHost(t, t.TempDir())
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "arm64" {
+		r := Deploy(config.RemoteTarget{Host: "db1"}, DeployOptions{Action: DeployInstall, BinaryDir: t.TempDir()})
+		assert.ErrorContains(t, r.Err, "no binary for linux/arm64")
+	}
+
+	r := Deploy(config.RemoteTarget{Host: "db1", AgentPath: "gosysmesh"}, DeployOptions{Action: DeployInstall})
+	assert.ErrorContains(t, r.Err, "must contain a directory")
+}
@@ -0,0 +1,322 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/knownhosts"
+	"github.com/ChristianThibeault/gosysmesh/internal/sshagent"
+)
+
+// CheckStatus is the outcome of a diagnostic step.
+type CheckStatus int
+
+const (
+	CheckPass CheckStatus = iota
+	CheckFail
+	CheckSkip
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckPass:
+		return "PASS"
+	case CheckFail:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
+
+// CheckResult is the result of one diagnostic step against a target.
+type CheckResult struct {
+	Step   string
+	Status CheckStatus
+	Detail string
+}
+
+// doctorTimeout bounds each network step of Diagnose.
+const doctorTimeout = 10 * time.Second
+
+// requiredTools are the remote commands the collection commands rely on.
+var requiredTools = []string{"ps", "top", "free", "df", "awk", "sed", "grep", "date", "uname"}
+
+// Diagnose checks, step by step, everything needed to collect from target:
+// name resolution, TCP reachability (through the jump hosts if any), the host
+// key in known_hosts, SSH key permissions, authentication and the remote
+// commands gosysmesh runs. Steps that depend on a failed step are skipped.
+func Diagnose(target config.RemoteTarget) []CheckResult {
+	var results []CheckResult
+	add := func(step string, status CheckStatus, format string, args ...interface{}) CheckStatus {
+		results = append(results, CheckResult{Step: step, Status: status, Detail: fmt.Sprintf(format, args...)})
+		return status
+	}
+
+	chain, err := target.JumpChain()
+	if err != nil {
+		add("config", CheckFail, "invalid jump hosts: %v", err)
+		return results
+	}
+
+	// The first host we connect to directly is the first hop, or the target.
+	firstHost, firstPort := target.Host, target.Port
+	if len(chain) > 0 {
+		firstHost, firstPort = chain[0].Host, chain[0].Port
+	}
+
+	reachable := true
+	if addrs, err := resolveHost(firstHost); err != nil {
+		reachable = add("dns", CheckFail, "%s: %v", firstHost, err) == CheckPass
+	} else {
+		add("dns", CheckPass, "%s → %s", firstHost, strings.Join(addrs, ", "))
+	}
+	if len(chain) > 0 {
+		add("dns (target)", CheckSkip, "%s is resolved by the jump host", target.Host)
+	}
+
+	if !reachable {
+		add("tcp", CheckSkip, "name resolution failed")
+	} else if banner, err := checkTCP(firstHost, firstPort, doctorTimeout); err != nil {
+		reachable = add("tcp", CheckFail, "%s:%d: %v", firstHost, firstPort, err) == CheckPass
+	} else {
+		add("tcp", CheckPass, "%s:%d %s", firstHost, firstPort, banner)
+	}
+
+	if len(chain) > 0 {
+		if !reachable {
+			add("tcp via jump", CheckSkip, "jump host unreachable")
+		} else if banner, err := checkTCPViaJump(target, chain, doctorTimeout); err != nil {
+			reachable = add("tcp via jump", CheckFail, "%s:%d: %v", target.Host, target.Port, err) == CheckPass
+		} else {
+			add("tcp via jump", CheckPass, "%s:%d %s", target.Host, target.Port, banner)
+		}
+	}
+
+	name := knownHostsName(target.Host, target.Port)
+	switch keys, path, err := recordedHostKeys(target); {
+	case err != nil:
+		add("host key", CheckSkip, "%v", err)
+	case target.KeyPolicy() == config.HostKeyPinned && len(pinnedKeys(keys, target.HostKeyFingerprints)) < len(keys):
+		add("host key", CheckFail, "%s in %s does not match host_key_fingerprints (run gosysmesh hostkeys verify)", name, path)
+	case len(keys) > 0:
+		add("host key", CheckPass, "%s found in %s", name, path)
+	case target.KeyPolicy() == config.HostKeyStrict:
+		add("host key", CheckFail, "%s not in known_hosts (run ssh-keyscan or gosysmesh config init)", name)
+	default:
+		add("host key", CheckPass, "%s not recorded yet; checked on first connection (%s policy)", name, target.KeyPolicy())
+	}
+
+	keysOK := true
+	var keys []string
+	if target.SSHKey != "" {
+		keys = append(keys, target.SSHKey)
+	}
+	for _, hop := range chain {
+		if hop.SSHKeyPath != "" {
+			keys = append(keys, hop.SSHKeyPath)
+		}
+	}
+	for _, key := range keys {
+		if err := checkKeyPermissions(key); err != nil {
+			keysOK = add("key permissions", CheckFail, "%s: %v", key, err) == CheckPass
+		} else {
+			add("key permissions", CheckPass, "%s", key)
+		}
+	}
+
+	switch {
+	case target.HasPassphrase():
+		if socket, err := unlockKey(target); err != nil {
+			keysOK = add("passphrase", CheckFail, "%v", err) == CheckPass
+		} else {
+			add("passphrase", CheckPass, "%s unlocked into agent %s", target.SSHKey, socket)
+		}
+	case target.SSHKey == "" || target.IdentityAgent != "":
+		if socket := expandPath(target.AgentSocket()); socket == "" {
+			keysOK = add("agent", CheckFail, "%v", errNoAgent) == CheckPass
+		} else if agentKeys, err := sshagent.List(socket); err != nil {
+			keysOK = add("agent", CheckFail, "%s: %v", socket, err) == CheckPass
+		} else if len(agentKeys) == 0 {
+			keysOK = add("agent", CheckFail, "%s holds no keys (run ssh-add)", socket) == CheckPass
+		} else {
+			add("agent", CheckPass, "%s holds %d key(s)", socket, len(agentKeys))
+		}
+	}
+
+	authOK := false
+	switch {
+	case !reachable:
+		add("auth", CheckSkip, "host unreachable")
+	case !keysOK:
+		add("auth", CheckSkip, "unusable SSH key")
+	default:
+		if info, err := Probe(target); err != nil {
+			add("auth", CheckFail, "%s", sshFailureReason(err))
+		} else {
+			authOK = add("auth", CheckPass, "%s@%s (%s)", target.User, target.Host, info) == CheckPass
+		}
+	}
+
+	if !authOK {
+		add("remote commands", CheckSkip, "not authenticated")
+		return results
+	}
+	output, err := RunSSHCommandOpenSSH(target, CommandToolsCheck)
+	if err != nil {
+		add("remote commands", CheckFail, "%s", sshFailureReason(err))
+	} else if missing := parseMissingTools(output); len(missing) > 0 {
+		add("remote commands", CheckFail, "missing: %s", strings.Join(missing, ", "))
+	} else {
+		add("remote commands", CheckPass, "%s, /proc", strings.Join(requiredTools, ", "))
+	}
+
+	return results
+}
+
+func resolveHost(host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+// checkTCP connects to host:port and returns the SSH server banner.
+func checkTCP(host string, port int, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	return readBanner(conn)
+}
+
+// checkTCPViaJump opens a stdio tunnel to the target through the jump chain
+// and returns the SSH server banner received through it.
+func checkTCPViaJump(target config.RemoteTarget, chain []config.JumpConfig, timeout time.Duration) (string, error) {
+	last := chain[len(chain)-1]
+	args := []string{"-p", strconv.Itoa(last.Port)}
+	if last.SSHKeyPath != "" {
+		args = append(args, "-i", expandTilde(os.ExpandEnv(last.SSHKeyPath)))
+	}
+	hopOpts, err := hopHostKeyOptions(target)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, sshOptions...)
+	if hopOpts != nil {
+		args = append(args, hopOpts...)
+	} else {
+		args = append(args, strictHostKeyOptions...)
+	}
+	args = append(args, jumpArgs(chain[:len(chain)-1], hopOpts, last.Host)...)
+	args = append(args, "-W", net.JoinHostPort(target.Host, strconv.Itoa(target.Port)), last.User+"@"+last.Host)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	defer func() {
+		cancel()
+		cmd.Wait()
+	}()
+
+	banner, err := readBanner(stdout)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return banner, nil
+}
+
+// readBanner reads the SSH identification line from r.
+func readBanner(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "SSH-") {
+		if err != nil {
+			return "", fmt.Errorf("no SSH banner: %w", err)
+		}
+		return "", fmt.Errorf("not an SSH server: %q", line)
+	}
+	return line, nil
+}
+
+// knownHostsName returns the name under which OpenSSH records host:port.
+func knownHostsName(host string, port int) string {
+	return knownhosts.Name(host, port)
+}
+
+// recordedHostKeys returns the keys recorded for target in the known_hosts
+// file its host key policy uses, and that file.
+func recordedHostKeys(target config.RemoteTarget) ([]knownhosts.Key, string, error) {
+	path, err := KnownHostsFile(target)
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := knownhosts.Load(path)
+	if err != nil {
+		return nil, path, err
+	}
+	return knownhosts.Lookup(entries, knownHostsName(target.Host, target.Port)), path, nil
+}
+
+// checkKeyPermissions verifies that a private key exists and is not
+// accessible to group or others, which ssh would refuse.
+func checkKeyPermissions(path string) error {
+	info, err := os.Stat(expandTilde(os.ExpandEnv(path)))
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("is a directory")
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("permissions %04o are too open (want 0600)", perm)
+	}
+	return nil
+}
+
+// sshFailureReason describes an ssh failure by its class and most useful line.
+func sshFailureReason(err error) string {
+	var re *Error
+	if errors.As(err, &re) {
+		return fmt.Sprintf("%s: %s", re.Class, re.Reason())
+	}
+	return err.Error()
+}
+
+// parseMissingTools extracts the names reported by the tools check command.
+func parseMissingTools(output string) []string {
+	var missing []string
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "missing:"); ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,84 @@
+package remote
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTCPReadsBanner(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		conn.Close()
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	banner, err := checkTCP("127.0.0.1", addr.Port, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "SSH-2.0-OpenSSH_9.6", banner)
+}
+
+func TestCheckTCPRejectsNonSSH(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n"))
+		conn.Close()
+	}()
+
+	_, err = checkTCP("127.0.0.1", ln.Addr().(*net.TCPAddr).Port, time.Second)
+	assert.ErrorContains(t, err, "not an SSH server")
+}
+
+func TestCheckKeyPermissions(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(key, []byte("key"), 0o600))
+	assert.NoError(t, checkKeyPermissions(key))
+
+	require.NoError(t, os.Chmod(key, 0o644))
+	assert.ErrorContains(t, checkKeyPermissions(key), "too open")
+
+	assert.Error(t, checkKeyPermissions(filepath.Join(dir, "missing")))
+}
+
+func TestParseMissingTools(t *testing.T) {
+	assert.Empty(t, parseMissingTools(""))
+	assert.Equal(t, []string{"top", "/proc"}, parseMissingTools("missing:top\nmissing:/proc\n"))
+}
+
+func TestSSHFailureReason(t *testing.T) {
+	err := &Error{
+		Class:  ClassAuth,
+		Host:   "host",
+		Stderr: "Warning: something\nuser@host: Permission denied (publickey).\n",
+		Err:    errors.New("exit status 255"),
+	}
+	assert.Equal(t, "auth: user@host: Permission denied (publickey).", sshFailureReason(err))
+	assert.Equal(t, "plain", sshFailureReason(errors.New("plain")))
+}
+
+func TestKnownHostsName(t *testing.T) {
+	assert.Equal(t, "db1", knownHostsName("db1", 22))
+	assert.Equal(t, "[db1]:2222", knownHostsName("db1", 2222))
+}
@@ -0,0 +1,175 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrorClass categorises remote collection failures so callers can react to
+// the kind of failure rather than its message.
+type ErrorClass int
+
+const (
+	// ClassConnection means the host could not be reached: DNS failure,
+	// refused or reset connections, unreachable networks.
+	ClassConnection ErrorClass = iota
+	// ClassAuth means the host was reached but rejected our credentials.
+	ClassAuth
+	// ClassHostKey means the host key is unknown or does not match known_hosts.
+	ClassHostKey
+	// ClassTimeout means connecting or running the command took too long.
+	ClassTimeout
+	// ClassCommand means the remote command ran but failed.
+	ClassCommand
+	// ClassParse means the remote command succeeded but its output was not understood.
+	ClassParse
+
+	numErrorClasses
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassConnection:
+		return "connection"
+	case ClassAuth:
+		return "auth"
+	case ClassHostKey:
+		return "hostkey"
+	case ClassTimeout:
+		return "timeout"
+	case ClassCommand:
+		return "command"
+	case ClassParse:
+		return "parse"
+	default:
+		return fmt.Sprintf("ErrorClass(%d)", int(c))
+	}
+}
+
+// ErrorClasses lists every class in order.
+func ErrorClasses() []ErrorClass {
+	classes := make([]ErrorClass, numErrorClasses)
+	for i := range classes {
+		classes[i] = ErrorClass(i)
+	}
+	return classes
+}
+
+// Error is a classified failure talking to a remote host.
+type Error struct {
+	Class  ErrorClass
+	Host   string
+	Stderr string // ssh or remote command stderr, if any
+	// ExitStatus is the remote command's exit status for ClassCommand errors.
+	ExitStatus int
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("%s error on %s: %v — stderr: %s", e.Class, e.Host, e.Err, e.Stderr)
+	}
+	return fmt.Sprintf("%s error on %s: %v", e.Class, e.Host, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the most useful single line describing the failure: the
+// last non-empty stderr line, or the underlying error.
+func (e *Error) Reason() string {
+	lines := strings.Split(strings.TrimSpace(e.Stderr), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+	return e.Err.Error()
+}
+
+// ClassOf returns the class of the first *Error in err's chain.
+func ClassOf(err error) (ErrorClass, bool) {
+	var re *Error
+	if errors.As(err, &re) {
+		return re.Class, true
+	}
+	return 0, false
+}
+
+// IsClass reports whether err is a remote error of the given class.
+func IsClass(err error, class ErrorClass) bool {
+	c, ok := ClassOf(err)
+	return ok && c == class
+}
+
+// Per-class failure counters since process start.
+var errorCounts [numErrorClasses]atomic.Int64
+
+// recordError increments the counter for err's class, if it has one.
+func recordError(err error) {
+	if c, ok := ClassOf(err); ok && c >= 0 && c < numErrorClasses {
+		errorCounts[c].Add(1)
+	}
+}
+
+// ErrorCounts returns how many collection failures of each class have
+// occurred since the process started.
+func ErrorCounts() map[ErrorClass]int64 {
+	counts := make(map[ErrorClass]int64, numErrorClasses)
+	for _, c := range ErrorClasses() {
+		counts[c] = errorCounts[c].Load()
+	}
+	return counts
+}
+
+// sshErrorPatterns map ssh(1) diagnostics to a class, checked in order.
+var sshErrorPatterns = []struct {
+	substr string
+	class  ErrorClass
+}{
+	{"REMOTE HOST IDENTIFICATION HAS CHANGED", ClassHostKey},
+	{"Host key verification failed", ClassHostKey},
+	{"No matching host key", ClassHostKey},
+	{"Permission denied", ClassAuth},
+	{"Too many authentication failures", ClassAuth},
+	{"Load key", ClassAuth},
+	{"no such identity", ClassAuth},
+	{"timed out", ClassTimeout},
+	{"Timeout", ClassTimeout},
+}
+
+// classifySSHError turns the result of running ssh into a classified Error.
+// ssh exits with 255 for its own failures; any other exit status is the
+// remote command's.
+func classifySSHError(host string, err error, stderr string, ctxErr error) *Error {
+	e := &Error{Class: ClassConnection, Host: host, Stderr: strings.TrimSpace(stderr), Err: err}
+
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		e.Class = ClassTimeout
+		return e
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		// ssh could not be started at all
+		return e
+	}
+	if exitErr.ExitCode() != 255 {
+		e.Class = ClassCommand
+		e.ExitStatus = exitErr.ExitCode()
+		return e
+	}
+
+	for _, p := range sshErrorPatterns {
+		if strings.Contains(stderr, p.substr) {
+			e.Class = p.class
+			break
+		}
+	}
+	return e
+}
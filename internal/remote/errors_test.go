@@ -0,0 +1,65 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exitError returns a real *exec.ExitError with the given status.
+func exitError(t *testing.T, status int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", status)).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected exit error, got %v", err)
+	}
+	return err
+}
+
+func TestClassifySSHError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		stderr string
+		ctxErr error
+		want   ErrorClass
+	}{
+		{"refused", exitError(t, 255), "ssh: connect to host db1 port 22: Connection refused", nil, ClassConnection},
+		{"dns", exitError(t, 255), "ssh: Could not resolve hostname db1: Name or service not known", nil, ClassConnection},
+		{"auth", exitError(t, 255), "admin@db1: Permission denied (publickey).", nil, ClassAuth},
+		{"unknown host key", exitError(t, 255), "No ED25519 host key is known for db1 and you have requested strict checking.\nHost key verification failed.", nil, ClassHostKey},
+		{"changed host key", exitError(t, 255), "@ WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED! @", nil, ClassHostKey},
+		{"connect timeout", exitError(t, 255), "ssh: connect to host db1 port 22: Connection timed out", nil, ClassTimeout},
+		{"deadline", errors.New("signal: killed"), "", context.DeadlineExceeded, ClassTimeout},
+		{"remote command", exitError(t, 1), "ps: unknown option", nil, ClassCommand},
+		{"ssh missing", exec.ErrNotFound, "", nil, ClassConnection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := classifySSHError("db1", tt.err, tt.stderr, tt.ctxErr)
+			assert.Equal(t, tt.want, e.Class)
+
+			wrapped := fmt.Errorf("failed to run remote ps on db1: %w", e)
+			class, ok := ClassOf(wrapped)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, class)
+			assert.True(t, IsClass(wrapped, tt.want))
+		})
+	}
+}
+
+func TestRecordErrorCounts(t *testing.T) {
+	before := ErrorCounts()
+	recordError(fmt.Errorf("wrapped: %w", &Error{Class: ClassParse, Host: "db1", Err: errors.New("bad")}))
+	recordError(errors.New("unclassified"))
+
+	after := ErrorCounts()
+	assert.Equal(t, before[ClassParse]+1, after[ClassParse])
+	assert.Equal(t, before[ClassAuth], after[ClassAuth])
+}
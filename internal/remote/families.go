@@ -0,0 +1,119 @@
+package remote
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+)
+
+// Has reports whether family was requested in this collection.
+func (m *RemoteMetrics) Has(family config.MetricFamily) bool {
+	return hasFamily(m.Families, family)
+}
+
+func hasFamily(families []config.MetricFamily, family config.MetricFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeFamilies returns the known families among families, without
+// duplicates and in the order of config.MetricFamilies.
+func normalizeFamilies(families []config.MetricFamily) []config.MetricFamily {
+	var result []config.MetricFamily
+	for _, f := range config.MetricFamilies {
+		if hasFamily(families, f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// familySubsets returns every non-empty proper subset of the metric
+// families, each in the order of config.MetricFamilies. Collecting all of
+// them is CommandCollect.
+func familySubsets() [][]config.MetricFamily {
+	all := config.MetricFamilies
+	var subsets [][]config.MetricFamily
+	for mask := 1; mask < 1<<len(all)-1; mask++ {
+		var subset []config.MetricFamily
+		for i, f := range all {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, f)
+			}
+		}
+		subsets = append(subsets, subset)
+	}
+	return subsets
+}
+
+// collectCommandID returns the command collecting families, e.g.
+// "collect-system-disk". families must be normalized.
+func collectCommandID(families []config.MetricFamily) CommandID {
+	if len(families) == len(config.MetricFamilies) {
+		return CommandCollect
+	}
+	names := make([]string, len(families))
+	for i, f := range families {
+		names[i] = string(f)
+	}
+	return CommandID(string(CommandCollect) + "-" + strings.Join(names, "-"))
+}
+
+// restrict drops whatever was collected beyond families, so that a
+// collection that cannot be split, such as the agent's, reports only the
+// requested families.
+func (m *RemoteMetrics) restrict(families []config.MetricFamily) {
+	m.Families = families
+	if !m.Has(config.FamilyProcesses) {
+		m.Processes, m.ProcessErr = nil, nil
+	}
+	if !m.Has(config.FamilySystem) {
+		m.SystemErr = nil
+		m.RemoteTime, m.ClockSkew = time.Time{}, 0
+		if m.SystemStats != nil {
+			m.SystemStats.CPUPercent, m.SystemStats.MemUsedGB, m.SystemStats.MemTotalGB = 0, 0, 0
+		}
+	}
+	if !m.Has(config.FamilyDisk) {
+		m.DiskErr = nil
+		if m.SystemStats != nil {
+			m.SystemStats.DiskUsedGB, m.SystemStats.DiskTotalGB = 0, 0
+		}
+	}
+	if !m.Has(config.FamilySystem) && !m.Has(config.FamilyDisk) {
+		m.SystemStats = nil
+	}
+}
+
+// failed reports whether every requested family failed, and joins their errors.
+func (m *RemoteMetrics) failed() error {
+	var errs []error
+	for _, f := range m.Families {
+		err := m.familyErr(f)
+		if err == nil {
+			return nil
+		}
+		// System stats and disk usage collected by one section fail together
+		if len(errs) == 0 || errs[len(errs)-1] != err {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *RemoteMetrics) familyErr(family config.MetricFamily) error {
+	switch family {
+	case config.FamilyProcesses:
+		return m.ProcessErr
+	case config.FamilySystem:
+		return m.SystemErr
+	default:
+		return m.DiskErr
+	}
+}
@@ -0,0 +1,339 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/knownhosts"
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
+)
+
+// Probe runs the probe command on target over the same transport used for
+// collection and returns its output.
+func Probe(target config.RemoteTarget) (string, error) {
+	out, err := RunSSHCommandOpenSSH(target, CommandProbe)
+	return strings.TrimSpace(out), err
+}
+
+// IsHostKeyError reports whether err comes from a failed host key check.
+func IsHostKeyError(err error) bool {
+	return IsClass(err, ClassHostKey)
+}
+
+// ErrHostKeyChanged means a host presented a key other than the one recorded
+// or pinned for it, which may be a man-in-the-middle attack.
+var ErrHostKeyChanged = errors.New("host key changed")
+
+// IsHostKeyChanged reports whether err comes from a host presenting a
+// different key than the one recorded or pinned for it, as opposed to an
+// unknown host.
+func IsHostKeyChanged(err error) bool {
+	if errors.Is(err, ErrHostKeyChanged) {
+		return true
+	}
+	var re *Error
+	return errors.As(err, &re) && re.Class == ClassHostKey &&
+		strings.Contains(re.Stderr, "REMOTE HOST IDENTIFICATION HAS CHANGED")
+}
+
+// managedPathRegex restricts the managed known_hosts path to characters
+// that survive being passed as an ssh option, unquoted, inside a
+// ProxyCommand.
+var managedPathRegex = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+
+// ManagedKnownHostsPath returns the known_hosts file gosysmesh maintains for
+// targets using the tofu and pinned host key policies.
+func ManagedKnownHostsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(home, ".gosysmesh", "known_hosts")
+	if !managedPathRegex.MatchString(path) {
+		return "", fmt.Errorf("managed known_hosts path %q contains characters ssh options cannot carry", path)
+	}
+	return path, nil
+}
+
+// KnownHostsFile returns the known_hosts file that holds target's keys under
+// its host key policy.
+func KnownHostsFile(target config.RemoteTarget) (string, error) {
+	if target.KeyPolicy() == config.HostKeyStrict {
+		return DefaultKnownHostsPath()
+	}
+	return ManagedKnownHostsPath()
+}
+
+// DefaultKnownHostsPath returns the user's OpenSSH known_hosts file.
+func DefaultKnownHostsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+// RecordHostKey fetches the host keys of host with ssh-keyscan and appends
+// them, hashed, to the known_hosts file at path. It can only reach hosts that
+// are directly accessible.
+func RecordHostKey(host string, port int, path string) error {
+	if err := validation.Host(host); err != nil {
+		return fmt.Errorf("invalid host: %w", err)
+	}
+
+	cmd := exec.Command("ssh-keyscan", "-H", "-T", "10", "-p", strconv.Itoa(port), host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ssh-keyscan error: %v — stderr: %s", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return fmt.Errorf("ssh-keyscan returned no keys for %s", host)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(stdout.Bytes()); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// scanHostKeys fetches the keys host:port presents; tests replace it.
+var scanHostKeys = keyscan
+
+// keyscan fetches the keys host:port presents with ssh-keyscan, which can
+// only reach hosts that are directly accessible.
+func keyscan(host string, port int) ([]knownhosts.Key, error) {
+	if err := validation.Host(host); err != nil {
+		return nil, fmt.Errorf("invalid host: %w", err)
+	}
+	cmd := exec.Command("ssh-keyscan", "-T", "10", "-p", strconv.Itoa(port), host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ssh-keyscan error: %v — stderr: %s", err, stderr.String())
+	}
+	keys := knownhosts.ParseKeys(stdout.Bytes())
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("ssh-keyscan returned no keys for %s", host)
+	}
+	return keys, nil
+}
+
+// checkPinnedHostKey makes sure the managed known_hosts holds only pinned
+// keys for target before ssh checks the host against it. When it holds none,
+// or the pins have changed, the keys the host presents are fetched and the
+// pinned ones recorded. A host presenting no pinned key fails with
+// ErrHostKeyChanged.
+func checkPinnedHostKey(target config.RemoteTarget) error {
+	path, err := ManagedKnownHostsPath()
+	if err != nil {
+		return err
+	}
+	entries, err := knownhosts.Load(path)
+	if err != nil {
+		return err
+	}
+	name := knownhosts.Name(target.Host, target.Port)
+	recorded := knownhosts.Lookup(entries, name)
+	if len(recorded) > 0 && len(pinnedKeys(recorded, target.HostKeyFingerprints)) == len(recorded) {
+		return nil
+	}
+
+	if chain, _ := target.JumpChain(); len(chain) > 0 {
+		return &Error{Class: ClassHostKey, Host: target.Host,
+			Err: fmt.Errorf("no pinned key for %s in %s, and hosts behind jump hosts cannot be scanned; add its key there", name, path)}
+	}
+	scanned, err := scanHostKeys(target.Host, target.Port)
+	if err != nil {
+		return &Error{Class: ClassConnection, Host: target.Host, Err: err}
+	}
+	pinned := pinnedKeys(scanned, target.HostKeyFingerprints)
+	if len(pinned) == 0 {
+		return &Error{Class: ClassHostKey, Host: target.Host,
+			Err: fmt.Errorf("%w: %s presents %s, none of them pinned", ErrHostKeyChanged, name, fingerprints(scanned))}
+	}
+	if _, err := knownhosts.Remove(path, name); err != nil {
+		return err
+	}
+	return knownhosts.Add(path, name, pinned)
+}
+
+// pinnedKeys returns the keys whose fingerprint is in pins.
+func pinnedKeys(keys []knownhosts.Key, pins []string) []knownhosts.Key {
+	var pinned []knownhosts.Key
+	for _, k := range keys {
+		for _, fp := range pins {
+			if k.Fingerprint() == fp {
+				pinned = append(pinned, k)
+				break
+			}
+		}
+	}
+	return pinned
+}
+
+func fingerprints(keys []knownhosts.Key) string {
+	fps := make([]string, len(keys))
+	for i, k := range keys {
+		fps[i] = k.Fingerprint()
+	}
+	return strings.Join(fps, ", ")
+}
+
+// HostKeyStatus is the outcome of comparing the keys a host presents with
+// those recorded for it.
+type HostKeyStatus int
+
+const (
+	// HostKeyMatch means the host presents a recorded key.
+	HostKeyMatch HostKeyStatus = iota
+	// HostKeyUnknown means no key is recorded for the host yet.
+	HostKeyUnknown
+	// HostKeyChanged means the host presents a different key than the
+	// recorded or pinned one.
+	HostKeyChanged
+	// HostKeyUnreachable means the host's keys could not be fetched.
+	HostKeyUnreachable
+)
+
+func (s HostKeyStatus) String() string {
+	switch s {
+	case HostKeyMatch:
+		return "ok"
+	case HostKeyUnknown:
+		return "unknown"
+	case HostKeyChanged:
+		return "CHANGED"
+	case HostKeyUnreachable:
+		return "unreachable"
+	default:
+		return fmt.Sprintf("HostKeyStatus(%d)", int(s))
+	}
+}
+
+// HostKeyCheck describes the host keys of one target.
+type HostKeyCheck struct {
+	Name      string // host or [host]:port, as in known_hosts
+	Policy    string
+	File      string           // known_hosts file used under Policy
+	Recorded  []knownhosts.Key // keys in File
+	Presented []knownhosts.Key // keys the host presents, when fetched
+	Status    HostKeyStatus
+	Err       error
+}
+
+// ListHostKeys returns the keys recorded for target without contacting it.
+func ListHostKeys(target config.RemoteTarget) HostKeyCheck {
+	check := HostKeyCheck{Name: knownHostsName(target.Host, target.Port), Policy: target.KeyPolicy(), Status: HostKeyUnknown}
+	check.Recorded, check.File, check.Err = recordedHostKeys(target)
+	if len(check.Recorded) > 0 {
+		check.Status = HostKeyMatch
+	}
+	return check
+}
+
+// VerifyHostKeys fetches the keys target presents and compares them with
+// the recorded keys and, under the pinned policy, with the pinned
+// fingerprints.
+func VerifyHostKeys(target config.RemoteTarget) HostKeyCheck {
+	check := ListHostKeys(target)
+	if check.Err != nil {
+		check.Status = HostKeyUnreachable
+		return check
+	}
+	if chain, _ := target.JumpChain(); len(chain) > 0 {
+		check.Status = HostKeyUnreachable
+		check.Err = errors.New("hosts behind jump hosts cannot be scanned")
+		return check
+	}
+	check.Presented, check.Err = scanHostKeys(target.Host, target.Port)
+	if check.Err != nil {
+		check.Status = HostKeyUnreachable
+		return check
+	}
+
+	check.Status = compareHostKeys(check.Recorded, check.Presented)
+	if target.KeyPolicy() == config.HostKeyPinned && len(pinnedKeys(check.Presented, target.HostKeyFingerprints)) == 0 {
+		check.Status = HostKeyChanged
+		check.Err = fmt.Errorf("%w: none of the presented keys is pinned", ErrHostKeyChanged)
+	}
+	return check
+}
+
+// compareHostKeys reports whether presented agrees with recorded: a key type
+// recorded for the host must still be presented with the same key.
+func compareHostKeys(recorded, presented []knownhosts.Key) HostKeyStatus {
+	if len(recorded) == 0 {
+		return HostKeyUnknown
+	}
+	matched := false
+	for _, p := range presented {
+		sameType, same := false, false
+		for _, r := range recorded {
+			if r.Type == p.Type {
+				sameType = true
+				same = same || bytes.Equal(r.Blob, p.Blob)
+			}
+		}
+		if sameType && !same {
+			return HostKeyChanged
+		}
+		matched = matched || same
+	}
+	if !matched {
+		return HostKeyChanged
+	}
+	return HostKeyMatch
+}
+
+// PinHostKeys fetches the keys target presents and records them in the
+// managed known_hosts, replacing any recorded before. It returns the keys so
+// their fingerprints can be pinned in the config.
+func PinHostKeys(target config.RemoteTarget) ([]knownhosts.Key, error) {
+	if chain, _ := target.JumpChain(); len(chain) > 0 {
+		return nil, errors.New("hosts behind jump hosts cannot be scanned")
+	}
+	keys, err := scanHostKeys(target.Host, target.Port)
+	if err != nil {
+		return nil, err
+	}
+	path, err := ManagedKnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	name := knownHostsName(target.Host, target.Port)
+	if _, err := knownhosts.Remove(path, name); err != nil {
+		return nil, err
+	}
+	return keys, knownhosts.Add(path, name, keys)
+}
+
+// ForgetHostKeys removes the keys recorded for host:port from the managed
+// known_hosts, so that the tofu policy trusts the host's next key. It
+// returns how many keys were removed.
+func ForgetHostKeys(host string, port int) (int, error) {
+	path, err := ManagedKnownHostsPath()
+	if err != nil {
+		return 0, err
+	}
+	return knownhosts.Remove(path, knownHostsName(host, port))
+}
@@ -0,0 +1,157 @@
+package remote
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/knownhosts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ed25519Key = knownhosts.ParseKeys([]byte("h ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK8BKiG1djmT7q58qX6ECU+njqNWWpp37z/0M18qKXkF"))[0]
+	ecdsaKey   = knownhosts.ParseKeys([]byte("h ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ/fH9qBa8BZ/P7zMyM6vQT2idKH0BevUuMzRtGrmM8J6USIP6M+bnB1KuIO+UiZbAcluq+Yw1KuaJiOkiyWJ1w="))[0]
+	// otherEd25519Key is what a rebuilt host would present
+	otherEd25519Key = knownhosts.Key{Type: "ssh-ed25519", Blob: []byte("rebuilt")}
+)
+
+// fakeScan replaces ssh-keyscan with a function returning keys, and counts
+// the scans.
+func fakeScan(t *testing.T, keys ...knownhosts.Key) *int {
+	t.Helper()
+	scans := 0
+	orig := scanHostKeys
+	scanHostKeys = func(host string, port int) ([]knownhosts.Key, error) {
+		scans++
+		return keys, nil
+	}
+	t.Cleanup(func() { scanHostKeys = orig })
+	return &scans
+}
+
+func TestBuildSSHArgsHostKeyPolicies(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	managed := filepath.Join(home, ".gosysmesh", "known_hosts")
+
+	target := config.RemoteTarget{Host: "db1.internal", User: "monitor", Port: 22, SSHKey: "/keys/monitor"}
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "StrictHostKeyChecking=yes")
+	assert.NotContains(t, strings.Join(args, " "), "UserKnownHostsFile")
+
+	target.HostKeyPolicy = config.HostKeyTOFU
+	target.ProxyJump = "ops@bastion"
+	args, err = buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "StrictHostKeyChecking=accept-new")
+	assert.Contains(t, args, "UserKnownHostsFile="+managed)
+	assert.NotContains(t, args, "-J", "hops need the same options")
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "ProxyCommand=") {
+			assert.Contains(t, arg, "StrictHostKeyChecking=accept-new")
+			assert.Contains(t, arg, "UserKnownHostsFile="+managed)
+		}
+	}
+
+	target.HostKeyPolicy = config.HostKeyPinned
+	args, err = buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "StrictHostKeyChecking=yes")
+	assert.Contains(t, args, "UserKnownHostsFile="+managed)
+	assert.Contains(t, args, "-J", "hops of pinned targets are checked strictly")
+}
+
+func TestCheckPinnedHostKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	target := config.RemoteTarget{
+		Host: "db1.internal", User: "monitor", Port: 2222, SSHKey: "/keys/monitor",
+		HostKeyPolicy:       config.HostKeyPinned,
+		HostKeyFingerprints: []string{ed25519Key.Fingerprint()},
+	}
+	scans := fakeScan(t, ecdsaKey, ed25519Key)
+
+	// The first connection records only the pinned key
+	require.NoError(t, checkPinnedHostKey(target))
+	check := ListHostKeys(target)
+	require.NoError(t, check.Err)
+	assert.Equal(t, "[db1.internal]:2222", check.Name)
+	assert.Equal(t, []knownhosts.Key{ed25519Key}, check.Recorded)
+
+	// Later connections rely on the recorded key
+	require.NoError(t, checkPinnedHostKey(target))
+	assert.Equal(t, 1, *scans)
+
+	// A host presenting no pinned key is refused and its record kept
+	target.HostKeyFingerprints = []string{ecdsaKey.Fingerprint()}
+	fakeScan(t, otherEd25519Key)
+	err := checkPinnedHostKey(target)
+	require.Error(t, err)
+	assert.True(t, IsClass(err, ClassHostKey))
+	assert.True(t, IsHostKeyChanged(err))
+	assert.Equal(t, []knownhosts.Key{ed25519Key}, ListHostKeys(target).Recorded)
+
+	// Changing the pins re-records the keys the host presents
+	fakeScan(t, ecdsaKey, ed25519Key)
+	require.NoError(t, checkPinnedHostKey(target))
+	assert.Equal(t, []knownhosts.Key{ecdsaKey}, ListHostKeys(target).Recorded)
+}
+
+func TestVerifyPinAndForgetHostKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	target := config.RemoteTarget{Host: "db1.internal", User: "monitor", Port: 22, SSHKey: "/keys/monitor", HostKeyPolicy: config.HostKeyTOFU}
+
+	fakeScan(t, ed25519Key)
+	assert.Equal(t, HostKeyUnknown, VerifyHostKeys(target).Status)
+
+	keys, err := PinHostKeys(target)
+	require.NoError(t, err)
+	assert.Equal(t, []knownhosts.Key{ed25519Key}, keys)
+	assert.Equal(t, HostKeyMatch, VerifyHostKeys(target).Status)
+
+	fakeScan(t, otherEd25519Key)
+	check := VerifyHostKeys(target)
+	assert.Equal(t, HostKeyChanged, check.Status)
+	assert.Equal(t, []knownhosts.Key{otherEd25519Key}, check.Presented)
+
+	n, err := ForgetHostKeys("db1.internal", 22)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, HostKeyUnknown, VerifyHostKeys(target).Status)
+
+	target.ProxyJump = "bastion"
+	assert.Equal(t, HostKeyUnreachable, VerifyHostKeys(target).Status)
+}
+
+func TestCompareHostKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		recorded  []knownhosts.Key
+		presented []knownhosts.Key
+		want      HostKeyStatus
+	}{
+		{"nothing recorded", nil, []knownhosts.Key{ed25519Key}, HostKeyUnknown},
+		{"same key", []knownhosts.Key{ed25519Key}, []knownhosts.Key{ed25519Key}, HostKeyMatch},
+		{"extra key types presented", []knownhosts.Key{ed25519Key}, []knownhosts.Key{ecdsaKey, ed25519Key}, HostKeyMatch},
+		{"recorded type with a new key", []knownhosts.Key{ed25519Key}, []knownhosts.Key{ecdsaKey, otherEd25519Key}, HostKeyChanged},
+		{"recorded type no longer presented", []knownhosts.Key{ed25519Key}, []knownhosts.Key{ecdsaKey}, HostKeyChanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, compareHostKeys(tt.recorded, tt.presented))
+		})
+	}
+}
+
+func TestIsHostKeyChanged(t *testing.T) {
+	changed := &Error{Class: ClassHostKey, Host: "db1", Stderr: "@@@@@\n@    WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED!     @\n@@@@@", Err: errors.New("exit status 255")}
+	unknown := &Error{Class: ClassHostKey, Host: "db1", Stderr: "Host key verification failed.", Err: errors.New("exit status 255")}
+
+	assert.True(t, IsHostKeyChanged(changed))
+	assert.False(t, IsHostKeyChanged(unknown))
+	assert.False(t, IsHostKeyChanged(errors.New("REMOTE HOST IDENTIFICATION HAS CHANGED")), "only classified errors")
+}
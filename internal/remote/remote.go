@@ -1,6 +1,7 @@
 package remote
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,50 +11,246 @@ import (
 	"github.com/ChristianThibeault/gosysmesh/internal/config"
 )
 
+// runSSH executes remote commands; tests replace it to simulate hosts.
+var runSSH = RunSSHCommandOpenSSH
+
 // RemoteMetrics holds data collected from a remote server
 type RemoteMetrics struct {
 	Host        string
 	Timestamp   time.Time
 	Processes   []collector.MonitoredProcess
 	SystemStats *collector.SystemStats
+
+	// Families lists the metric families that were requested. Fields of
+	// the other families are left empty.
+	Families []config.MetricFamily
+
+	// RemoteTime is the remote host's clock as reported alongside the system stats.
+	RemoteTime time.Time
+	// ClockSkew is how far the remote clock is ahead of (positive) or behind
+	// (negative) the local clock, estimated at the midpoint of the round-trip.
+	ClockSkew time.Duration
+	// RoundTrip is the wall time of the SSH call that carried the stats.
+	RoundTrip time.Duration
+
+	// ProcessErr, SystemErr and DiskErr record why a family is missing
+	// when another family was still collected. System stats and disk usage
+	// collected together fail together, with the same error.
+	ProcessErr error
+	SystemErr  error
+	DiskErr    error
+
+	// Agent is set when the metrics came from the gosysmesh agent rather
+	// than from parsing ps and top output.
+	Agent bool
+	// AgentFallback explains why a target in agent mode was scraped instead.
+	AgentFallback error
+}
+
+// Degraded reports whether any family failed to collect.
+func (m *RemoteMetrics) Degraded() bool {
+	return m.ProcessErr != nil || m.SystemErr != nil || m.DiskErr != nil
+}
+
+// clockSample pairs a remote clock reading with the local times bracketing
+// the SSH call that produced it.
+type clockSample struct {
+	Remote   time.Time
+	Sent     time.Time
+	Received time.Time
+}
+
+// RoundTrip returns the duration of the SSH call.
+func (c clockSample) RoundTrip() time.Duration {
+	return c.Received.Sub(c.Sent)
+}
+
+// Skew estimates the remote clock offset assuming the remote reading was
+// taken halfway through the round-trip.
+func (c clockSample) Skew() time.Duration {
+	return c.Remote.Sub(c.Sent.Add(c.RoundTrip() / 2))
+}
+
+// SkewExceeded reports whether the absolute clock skew is larger than max.
+// A zero max disables the check, and so does a missing remote clock reading.
+func (m *RemoteMetrics) SkewExceeded(max time.Duration) bool {
+	if max <= 0 || m.RemoteTime.IsZero() {
+		return false
+	}
+	skew := m.ClockSkew
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew > max
 }
 
-// CollectRemoteStats collects process info and system stats from a remote server via OpenSSH
+// CollectRemoteStats collects process info and system stats from a remote server via OpenSSH.
+// If only one section fails, the other is still returned, with the failure
+// recorded in ProcessErr or SystemErr. An error is returned only when nothing
+// could be collected. Failures carry an *Error whose class is counted in ErrorCounts.
 func CollectRemoteStats(target config.RemoteTarget) (*RemoteMetrics, error) {
-	// Collect process info using safe command builder
-	cmd, err := BuildPsCommand(target.User)
+	return CollectRemoteFamilies(target, config.MetricFamilies)
+}
+
+// CollectRemoteFamilies is CollectRemoteStats restricted to families, for
+// families that are due on their own schedule. Scraping only runs the
+// commands the families need; the agent always collects everything and the
+// rest is dropped.
+func CollectRemoteFamilies(target config.RemoteTarget, families []config.MetricFamily) (*RemoteMetrics, error) {
+	metrics, err := collectRemoteStats(target, normalizeFamilies(families))
 	if err != nil {
-		return nil, fmt.Errorf("failed to build ps command: %w", err)
+		recordError(err)
+		return nil, err
 	}
-	output, err := RunSSHCommandOpenSSH(target.User, target.Host, target.Port, target.SSHKey, target.ProxyJump, cmd)
-	if err != nil {
-		return nil, fmt.Errorf("failed to run remote ps on %s: %w", target.Host, err)
+	recordError(metrics.ProcessErr)
+	recordError(metrics.SystemErr)
+	if metrics.DiskErr != metrics.SystemErr {
+		recordError(metrics.DiskErr)
 	}
+	return metrics, nil
+}
+
+func collectRemoteStats(target config.RemoteTarget, families []config.MetricFamily) (*RemoteMetrics, error) {
+	if len(families) == 0 {
+		return nil, errors.New("no metric families to collect")
+	}
+	if !target.UsesAgent() {
+		return scrapeRemoteStats(target, families)
+	}
+
+	metrics, err := collectViaAgent(target)
+	if err == nil {
+		metrics.restrict(families)
+		if err := metrics.failed(); err != nil {
+			return nil, err
+		}
+		return metrics, nil
+	}
+	if !errors.Is(err, errAgentUnavailable) {
+		return nil, err
+	}
+	metrics, scrapeErr := scrapeRemoteStats(target, families)
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
+	metrics.AgentFallback = err
+	return metrics, nil
+}
 
-	procs := parseProcessOutput(output, target.ProcessFilters)
+// scrapeRemoteStats collects families from target by parsing the output of
+// standard tools.
+func scrapeRemoteStats(target config.RemoteTarget, families []config.MetricFamily) (*RemoteMetrics, error) {
+	var args []string
+	if hasFamily(families, config.FamilyProcesses) {
+		args = append(args, target.User)
+	}
 
-	// Collect system stats
-	systemStats, err := collectRemoteSystemStats(target)
+	// The families are collected by one script so that they share a single
+	// round-trip and describe the same instant
+	clock := clockSample{Sent: time.Now()}
+	output, err := runSSH(target, collectCommandID(families), args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect system stats from %s: %w", target.Host, err)
+		return nil, fmt.Errorf("failed to collect from %s: %w", target.Host, err)
+	}
+	clock.Received = time.Now()
+
+	sections, err := splitSections(output)
+	if err != nil {
+		return nil, &Error{Class: ClassParse, Host: target.Host, Err: err}
+	}
+
+	metrics := &RemoteMetrics{Host: target.Host, RoundTrip: clock.RoundTrip(), Families: families}
+
+	if metrics.Has(config.FamilyProcesses) {
+		if ps, err := sectionOutput(target.Host, sections, sectionProcesses); err != nil {
+			metrics.ProcessErr = fmt.Errorf("failed to run remote ps on %s: %w", target.Host, err)
+		} else {
+			// ps reports elapsed time rather than a wall-clock start, so anchoring it
+			// at the midpoint of the round-trip expresses start times on the local
+			// clock and keeps any remote clock offset out of the result.
+			metrics.Processes = parseProcessOutput(ps.Stdout, target.ProcessFilters, clock.Sent.Add(clock.RoundTrip()/2))
+		}
+	}
+
+	system, disk := metrics.Has(config.FamilySystem), metrics.Has(config.FamilyDisk)
+	if system || disk {
+		systemStats, remoteTime, err := systemStatsSection(target.Host, sections, system, disk)
+		if err != nil {
+			err = fmt.Errorf("failed to collect system stats from %s: %w", target.Host, err)
+			if system {
+				metrics.SystemErr = err
+			}
+			if disk {
+				metrics.DiskErr = err
+			}
+		} else {
+			metrics.SystemStats = systemStats
+			if system {
+				clock.Remote = remoteTime
+				metrics.RemoteTime = remoteTime
+				metrics.ClockSkew = clock.Skew()
+			}
+		}
+	}
+
+	if err := metrics.failed(); err != nil {
+		return nil, err
+	}
+	metrics.Timestamp = time.Now()
+	return metrics, nil
+}
+
+// sectionOutput returns the named section, or a command error if it is
+// missing or exited with a non-zero status.
+func sectionOutput(host string, sections map[string]*section, name string) (*section, error) {
+	sec, ok := sections[name]
+	if !ok {
+		return nil, &Error{Class: ClassParse, Host: host, Err: fmt.Errorf("missing %s section", name)}
+	}
+	if sec.Status != 0 {
+		return nil, &Error{Class: ClassCommand, Host: host, Stderr: sec.Stderr, ExitStatus: sec.Status, Err: fmt.Errorf("exit status %d", sec.Status)}
 	}
+	return sec, nil
+}
 
-	return &RemoteMetrics{
-		Host:        target.Host,
-		Timestamp:   time.Now(),
-		Processes:   procs,
-		SystemStats: systemStats,
-	}, nil
+// systemStatsSection decodes the section holding system stats, disk usage or
+// both, depending on which were collected. The stats pipeline exits with the
+// status of its last stage, so a tool missing earlier in it only shows up as
+// unparseable output; when that output comes with stderr the failure is
+// reported as a command error rather than a parse error.
+func systemStatsSection(host string, sections map[string]*section, system, disk bool) (*collector.SystemStats, time.Time, error) {
+	name, parse := sectionStats, parseSystemStatsOutput
+	switch {
+	case !disk:
+		name, parse = sectionSystem, parseSystemOutput
+	case !system:
+		name, parse = sectionDisk, parseDiskOutput
+	}
+	sec, err := sectionOutput(host, sections, name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	stats, remoteTime, err := parse(sec.Stdout)
+	if err != nil {
+		class := ClassParse
+		if sec.Stderr != "" {
+			class = ClassCommand
+		}
+		return nil, time.Time{}, &Error{Class: class, Host: host, Stderr: sec.Stderr, Err: err}
+	}
+	return stats, remoteTime, nil
 }
 
-// parseProcessOutput parses `ps` command output and filters it
-func parseProcessOutput(output string, filters config.ProcessFilterConfig) []collector.MonitoredProcess {
+// parseProcessOutput parses `ps` command output and filters it. ref is the
+// local time at which ps is assumed to have run; start times are derived from
+// it and each process's elapsed time.
+func parseProcessOutput(output string, filters config.ProcessFilterConfig, ref time.Time) []collector.MonitoredProcess {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
 	var result []collector.MonitoredProcess
 
 	for _, line := range lines {
 		fields := strings.Fields(line)
-		if len(fields) < 8 {
+		if len(fields) < 7 {
 			continue
 		}
 
@@ -62,8 +259,8 @@ func parseProcessOutput(output string, filters config.ProcessFilterConfig) []col
 		cpu := fields[2]
 		mem := fields[3]
 		stat := fields[4]
-		start := strings.Join(fields[5:10], " ") // lstart is 5 fields
-		cmdline := strings.Join(fields[10:], " ") // everything after is the command
+		etimes := fields[5]
+		cmdline := strings.Join(fields[6:], " ") // everything after is the command
 
 
 
@@ -88,7 +285,15 @@ func parseProcessOutput(output string, filters config.ProcessFilterConfig) []col
 		if err != nil {
 			continue
 		}
+		elapsed, err := strconv.ParseInt(etimes, 10, 64)
+		if err != nil || elapsed < 0 {
+			continue
+		}
+		age := time.Duration(elapsed) * time.Second
 
+		// ps reports the command line as the name too; both are redacted
+		// once filtering is done
+		cmdline = collector.RedactCmdline(cmdline)
 		result = append(result, collector.MonitoredProcess{
 			PID:       int32(pidInt),
 			User:      user,
@@ -97,65 +302,120 @@ func parseProcessOutput(output string, filters config.ProcessFilterConfig) []col
 			CPU:       cpuFloat,
 			MEM:       memFloat,
 			Status:    stat,
-			StartTime: start,
+			StartTime: ref.Add(-age),
+			Age:       age,
 		})
 	}
 
 	return result
 }
 
-// collectRemoteSystemStats collects system stats from a remote server via SSH
-func collectRemoteSystemStats(target config.RemoteTarget) (*collector.SystemStats, error) {
-	// Use pre-approved system stats command
-	cmd := BuildSystemStatsCommand()
-	
-	output, err := RunSSHCommandOpenSSH(target.User, target.Host, target.Port, target.SSHKey, target.ProxyJump, cmd)
+// parseSystemStatsOutput parses system stats from remote command output. The
+// first field is the remote epoch time, followed by CPU, memory and disk usage,
+// each tool's values on a line of their own. Fields are split on any
+// whitespace, so stdout split around a stray stderr line still parses.
+func parseSystemStatsOutput(output string) (*collector.SystemStats, time.Time, error) {
+	parts := strings.Fields(strings.TrimSpace(output))
+	if len(parts) < 6 {
+		return nil, time.Time{}, fmt.Errorf("invalid system stats output: %s", output)
+	}
+
+	stats, remoteTime, err := parseSystemFields(parts[:4])
 	if err != nil {
-		return nil, fmt.Errorf("failed to run system stats command: %w", err)
+		return nil, time.Time{}, err
 	}
+	if err := parseDiskFields(parts[4:6], stats); err != nil {
+		return nil, time.Time{}, err
+	}
+	return stats, remoteTime, nil
+}
 
-	return parseSystemStatsOutput(output)
+// parseSystemOutput parses the output of the system section alone: the
+// remote epoch time, CPU and memory usage.
+func parseSystemOutput(output string) (*collector.SystemStats, time.Time, error) {
+	parts := strings.Fields(strings.TrimSpace(output))
+	if len(parts) < 4 {
+		return nil, time.Time{}, fmt.Errorf("invalid system stats output: %s", output)
+	}
+	return parseSystemFields(parts[:4])
 }
 
-// parseSystemStatsOutput parses system stats from remote command output
-func parseSystemStatsOutput(output string) (*collector.SystemStats, error) {
+// parseDiskOutput parses the output of the disk section alone. It carries no
+// remote time.
+func parseDiskOutput(output string) (*collector.SystemStats, time.Time, error) {
 	parts := strings.Fields(strings.TrimSpace(output))
-	if len(parts) < 5 {
-		return nil, fmt.Errorf("invalid system stats output: %s", output)
+	if len(parts) < 2 {
+		return nil, time.Time{}, fmt.Errorf("invalid disk usage output: %s", output)
+	}
+	stats := &collector.SystemStats{Timestamp: time.Now()}
+	if err := parseDiskFields(parts[:2], stats); err != nil {
+		return nil, time.Time{}, err
 	}
+	return stats, time.Time{}, nil
+}
 
-	cpuPercent, err := strconv.ParseFloat(parts[0], 64)
+// parseSystemFields parses the remote epoch time, CPU percentage, and used
+// and total memory in MB.
+func parseSystemFields(parts []string) (*collector.SystemStats, time.Time, error) {
+	remoteTime, err := parseEpoch(parts[0])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CPU: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to parse remote time: %w", err)
 	}
 
-	memUsed, err := strconv.ParseFloat(parts[1], 64)
+	cpuPercent, err := strconv.ParseFloat(parts[1], 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse memory used: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to parse CPU: %w", err)
 	}
 
-	memTotal, err := strconv.ParseFloat(parts[2], 64)
+	memUsed, err := strconv.ParseFloat(parts[2], 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse memory total: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to parse memory used: %w", err)
 	}
 
-	diskUsed, err := strconv.ParseFloat(parts[3], 64)
+	memTotal, err := strconv.ParseFloat(parts[3], 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse disk used: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to parse memory total: %w", err)
 	}
 
-	diskTotal, err := strconv.ParseFloat(parts[4], 64)
+	return &collector.SystemStats{
+		Timestamp:  time.Now(),
+		CPUPercent: cpuPercent,
+		MemUsedGB:  memUsed / 1024,
+		MemTotalGB: memTotal / 1024,
+	}, remoteTime, nil
+}
+
+// parseDiskFields parses used and total disk space in GB into stats.
+func parseDiskFields(parts []string, stats *collector.SystemStats) error {
+	diskUsed, err := strconv.ParseFloat(parts[0], 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse disk total: %w", err)
+		return fmt.Errorf("failed to parse disk used: %w", err)
 	}
 
-	return &collector.SystemStats{
-		Timestamp:   time.Now(),
-		CPUPercent:  cpuPercent,
-		MemUsedGB:   memUsed / 1024,
-		MemTotalGB:  memTotal / 1024,
-		DiskUsedGB:  diskUsed,
-		DiskTotalGB: diskTotal,
-	}, nil
+	diskTotal, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse disk total: %w", err)
+	}
+
+	stats.DiskUsedGB, stats.DiskTotalGB = diskUsed, diskTotal
+	return nil
+}
+
+// parseEpoch parses the output of `date +%s.%N`. Implementations of date that
+// do not support %N leave it unexpanded, in which case only the seconds are used.
+func parseEpoch(s string) (time.Time, error) {
+	secPart, fracPart, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secPart, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var nsec int64
+	if len(fracPart) == 9 {
+		if n, err := strconv.ParseInt(fracPart, 10, 64); err == nil {
+			nsec = n
+		}
+	}
+	return time.Unix(sec, nsec), nil
 }
 
@@ -0,0 +1,302 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/collector"
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessOutputStartTime(t *testing.T) {
+	output := `  812 postgres  1.5  3.2 Ss     273600 /usr/lib/postgresql/15/bin/postgres -D /var/lib/postgresql
+ 9001 postgres  0.0  0.1 S          42 postgres: checkpointer
+ 1234 nobody    0.0  0.0 S          10 /usr/bin/unrelated`
+	filters := config.ProcessFilterConfig{Keywords: []string{"postgres"}}
+	ref := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	procs := parseProcessOutput(output, filters, ref)
+	assert.Len(t, procs, 2)
+
+	assert.Equal(t, int32(812), procs[0].PID)
+	assert.Equal(t, 76*time.Hour, procs[0].Age)
+	assert.Equal(t, time.Date(2025, 3, 7, 8, 0, 0, 0, time.UTC), procs[0].StartTime)
+	assert.Equal(t, "/usr/lib/postgresql/15/bin/postgres -D /var/lib/postgresql", procs[0].Cmdline)
+
+	assert.Equal(t, 42*time.Second, procs[1].Age)
+	assert.Equal(t, ref.Add(-42*time.Second), procs[1].StartTime)
+}
+
+func TestParseProcessOutputRedactsCmdline(t *testing.T) {
+	r, err := redact.New([]string{`--license (\S+)`})
+	require.NoError(t, err)
+	collector.SetRedactor(r)
+	t.Cleanup(func() { collector.SetRedactor(nil) })
+
+	output := `  812 app  0.5  1.0 S  60 /opt/app/bin/server --db-password=hunter2 --license ABC-123`
+	filters := config.ProcessFilterConfig{Keywords: []string{"hunter2"}}
+
+	procs := parseProcessOutput(output, filters, time.Now())
+	require.Len(t, procs, 1, "filters see the original command line")
+	assert.Equal(t, "/opt/app/bin/server --db-password=[REDACTED] --license [REDACTED]", procs[0].Cmdline)
+	assert.Equal(t, procs[0].Cmdline, procs[0].Name)
+}
+
+func TestParseProcessOutputSkipsMalformedLines(t *testing.T) {
+	output := `  812 postgres  1.5  3.2 Ss  notanumber postgres
+  813 postgres  1.5  3.2 Ss`
+	filters := config.ProcessFilterConfig{Keywords: []string{"postgres"}}
+
+	procs := parseProcessOutput(output, filters, time.Now())
+	assert.Empty(t, procs)
+}
+
+func TestParseSystemStatsOutputRemoteTime(t *testing.T) {
+	stats, remoteTime, err := parseSystemStatsOutput("1741608000.250000000\n12.5\n2048 8192 45.2 100.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, stats.CPUPercent)
+	assert.Equal(t, 2.0, stats.MemUsedGB)
+	assert.Equal(t, 100.0, stats.DiskTotalGB)
+	assert.Equal(t, time.Unix(1741608000, 250000000), remoteTime)
+
+	// date implementations without %N support leave it unexpanded
+	_, remoteTime, err = parseSystemStatsOutput("1741608000.N 12.5 2048 8192 45.2 100.0")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1741608000, 0), remoteTime)
+
+	_, _, err = parseSystemStatsOutput("12.5 2048 8192 45.2 100.0")
+	assert.Error(t, err)
+}
+
+func TestClockSampleSkew(t *testing.T) {
+	sent := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	clock := clockSample{
+		Sent:     sent,
+		Received: sent.Add(200 * time.Millisecond),
+		Remote:   sent.Add(100*time.Millisecond + 3*time.Second),
+	}
+	assert.Equal(t, 200*time.Millisecond, clock.RoundTrip())
+	assert.Equal(t, 3*time.Second, clock.Skew())
+
+	m := &RemoteMetrics{RemoteTime: clock.Remote, ClockSkew: -3 * time.Second}
+	assert.True(t, m.SkewExceeded(2*time.Second))
+	assert.False(t, m.SkewExceeded(5*time.Second))
+	assert.False(t, m.SkewExceeded(0))
+}
+
+// fakeSSH replaces runSSH for the duration of a test with respond.
+func fakeSSH(t *testing.T, respond func() (string, error)) {
+	t.Helper()
+	orig := runSSH
+	t.Cleanup(func() { runSSH = orig })
+	runSSH = func(target config.RemoteTarget, id CommandID, args ...string) (string, error) {
+		return respond()
+	}
+}
+
+// scriptOutput builds the output of the collection script from per-section
+// stdout, stderr and exit status.
+func scriptOutput(psOut, psErr string, psStatus int, statsOut, statsErr string, statsStatus int) string {
+	var b strings.Builder
+	for _, sec := range []struct {
+		name, out, err string
+		status         int
+	}{{sectionProcesses, psOut, psErr, psStatus}, {sectionStats, statsOut, statsErr, statsStatus}} {
+		fmt.Fprintf(&b, "%s%s\n%s\n", sectionMarker, sec.name, sec.out)
+		fmt.Fprintf(&b, "%s%d\n", statusMarker, sec.status)
+		if sec.err != "" {
+			fmt.Fprintf(&b, "%s%s\n", stderrMarker, sec.err)
+		}
+	}
+	return b.String()
+}
+
+func TestSplitSections(t *testing.T) {
+	output := "Welcome to db1\n" +
+		"@@gosysmesh:section:ps\n" +
+		"  812 postgres 1.5 3.2 Ss 100 postgres\n" +
+		"\n@@gosysmesh:status:0\n" +
+		"@@gosysmesh:section:stats\n" +
+		"1741608000.000000000\n" +
+		"@@gosysmesh:stderr:sh: 1: top: not found\n" +
+		"2048 8192 45.2 100.0\n" +
+		"@@gosysmesh:status:0\n"
+
+	sections, err := splitSections(output)
+	require.NoError(t, err)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "  812 postgres 1.5 3.2 Ss 100 postgres", sections[sectionProcesses].Stdout)
+	assert.Equal(t, "1741608000.000000000\n2048 8192 45.2 100.0", sections[sectionStats].Stdout)
+	assert.Equal(t, "sh: 1: top: not found", sections[sectionStats].Stderr)
+
+	_, err = splitSections("@@gosysmesh:section:ps\n  812 postgres")
+	assert.Error(t, err, "truncated output must not pass as a complete section")
+
+	_, err = splitSections("@@gosysmesh:section:ps\n@@gosysmesh:status:x\n")
+	assert.Error(t, err)
+}
+
+func TestSplitSectionsStderrAfterPartialLine(t *testing.T) {
+	// df complains after free's values were written without a newline
+	output := "@@gosysmesh:section:stats\n" +
+		"1741608000.000000000\n" +
+		"12.5\n" +
+		"2048 8192@@gosysmesh:stderr:df: /proc/mounts: Permission denied\n" +
+		"45.2 100.0\n" +
+		"\n@@gosysmesh:status:0\n"
+
+	sections, err := splitSections(output)
+	require.NoError(t, err)
+	assert.Equal(t, "df: /proc/mounts: Permission denied", sections[sectionStats].Stderr)
+
+	stats, _, err := parseSystemStatsOutput(sections[sectionStats].Stdout)
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, stats.MemTotalGB)
+	assert.Equal(t, 45.2, stats.DiskUsedGB)
+}
+
+func TestCollectRemoteStatsPartialResults(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:           "db1",
+		User:           "postgres",
+		ProcessFilters: config.ProcessFilterConfig{Keywords: []string{"postgres"}},
+	}
+	const (
+		psOut    = "812 postgres 1.5 3.2 Ss 100 postgres -D /data"
+		statsOut = "1741608000.000000000\n12.5\n2048 8192 45.2 100.0"
+	)
+
+	t.Run("single round-trip", func(t *testing.T) {
+		calls := 0
+		fakeSSH(t, func() (string, error) {
+			calls++
+			return scriptOutput(psOut, "", 0, statsOut, "", 0), nil
+		})
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+		assert.Len(t, m.Processes, 1)
+		assert.Equal(t, 12.5, m.SystemStats.CPUPercent)
+		assert.Equal(t, time.Unix(1741608000, 0), m.RemoteTime)
+		assert.False(t, m.Degraded())
+	})
+
+	t.Run("system stats fail", func(t *testing.T) {
+		fakeSSH(t, func() (string, error) {
+			return scriptOutput(psOut, "", 0, "1741608000.000000000\n\n2048 8192 45.2 100.0", "sh: 1: top: not found", 0), nil
+		})
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.Len(t, m.Processes, 1)
+		assert.Nil(t, m.SystemStats)
+		assert.True(t, IsClass(m.SystemErr, ClassCommand))
+		assert.NoError(t, m.ProcessErr)
+		assert.True(t, m.Degraded())
+		assert.False(t, m.SkewExceeded(time.Second), "no clock reading means no skew alert")
+	})
+
+	t.Run("processes fail", func(t *testing.T) {
+		fakeSSH(t, func() (string, error) {
+			return scriptOutput("", "error: user name does not exist", 1, statsOut, "", 0), nil
+		})
+		m, err := CollectRemoteStats(target)
+		require.NoError(t, err)
+		assert.Empty(t, m.Processes)
+		assert.NotNil(t, m.SystemStats)
+		assert.True(t, IsClass(m.ProcessErr, ClassCommand))
+		assert.True(t, m.Degraded())
+	})
+
+	t.Run("both fail", func(t *testing.T) {
+		fakeSSH(t, func() (string, error) {
+			return scriptOutput("", "ps: not found", 127, "", "", 0), nil
+		})
+		m, err := CollectRemoteStats(target)
+		assert.Nil(t, m)
+		assert.True(t, IsClass(err, ClassCommand))
+	})
+
+	t.Run("host unreachable", func(t *testing.T) {
+		fakeSSH(t, func() (string, error) {
+			return "", &Error{Class: ClassConnection, Host: "db1", Err: errors.New("exit status 255")}
+		})
+		_, err := CollectRemoteStats(target)
+		assert.True(t, IsClass(err, ClassConnection))
+	})
+}
+
+func TestCollectRemoteFamilies(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:           "db1",
+		User:           "postgres",
+		ProcessFilters: config.ProcessFilterConfig{Keywords: []string{"postgres"}},
+	}
+	section := func(name, out string, status int) string {
+		return fmt.Sprintf("%s%s\n%s\n%s%d\n", sectionMarker, name, out, statusMarker, status)
+	}
+	fake := func(t *testing.T, output string) (*CommandID, *[]string) {
+		var gotID CommandID
+		var gotArgs []string
+		orig := runSSH
+		t.Cleanup(func() { runSSH = orig })
+		runSSH = func(target config.RemoteTarget, id CommandID, args ...string) (string, error) {
+			gotID, gotArgs = id, args
+			return output, nil
+		}
+		return &gotID, &gotArgs
+	}
+
+	t.Run("processes only", func(t *testing.T) {
+		id, args := fake(t, section(sectionProcesses, "812 postgres 1.5 3.2 Ss 100 postgres -D /data", 0))
+		m, err := CollectRemoteFamilies(target, []config.MetricFamily{config.FamilyProcesses})
+		require.NoError(t, err)
+		assert.Equal(t, CommandID("collect-processes"), *id)
+		assert.Equal(t, []string{"postgres"}, *args)
+		assert.Len(t, m.Processes, 1)
+		assert.Nil(t, m.SystemStats)
+		assert.True(t, m.Has(config.FamilyProcesses))
+		assert.False(t, m.Has(config.FamilySystem))
+	})
+
+	t.Run("system and disk share the stats section", func(t *testing.T) {
+		id, args := fake(t, section(sectionStats, "1741608000.000000000\n12.5\n2048 8192 45.2 100.0", 0))
+		m, err := CollectRemoteFamilies(target, []config.MetricFamily{config.FamilyDisk, config.FamilySystem})
+		require.NoError(t, err)
+		assert.Equal(t, CommandID("collect-system-disk"), *id)
+		assert.Empty(t, *args)
+		assert.Equal(t, 12.5, m.SystemStats.CPUPercent)
+		assert.Equal(t, 45.2, m.SystemStats.DiskUsedGB)
+		assert.Equal(t, []config.MetricFamily{config.FamilySystem, config.FamilyDisk}, m.Families)
+	})
+
+	t.Run("disk only", func(t *testing.T) {
+		id, _ := fake(t, section(sectionDisk, "45.2 100.0\n", 0))
+		m, err := CollectRemoteFamilies(target, []config.MetricFamily{config.FamilyDisk})
+		require.NoError(t, err)
+		assert.Equal(t, CommandID("collect-disk"), *id)
+		assert.Equal(t, 100.0, m.SystemStats.DiskTotalGB)
+		assert.Zero(t, m.SystemStats.CPUPercent)
+		assert.True(t, m.RemoteTime.IsZero(), "disk usage carries no clock reading")
+	})
+
+	t.Run("the only family fails", func(t *testing.T) {
+		fake(t, section(sectionSystem, "", 127))
+		m, err := CollectRemoteFamilies(target, []config.MetricFamily{config.FamilySystem})
+		assert.Nil(t, m)
+		assert.True(t, IsClass(err, ClassCommand))
+	})
+
+	t.Run("stats failure degrades system and disk", func(t *testing.T) {
+		fake(t, section(sectionProcesses, "", 0)+section(sectionStats, "garbage", 0))
+		m, err := CollectRemoteFamilies(target, []config.MetricFamily{config.FamilyProcesses, config.FamilySystem, config.FamilyDisk})
+		require.NoError(t, err)
+		assert.True(t, IsClass(m.SystemErr, ClassParse))
+		assert.Equal(t, m.SystemErr, m.DiskErr)
+	})
+}
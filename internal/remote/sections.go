@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Markers delimiting the sections of the combined collection script. Each
+// section starts with a header line, ends with its exit status, and has its
+// stderr tagged line by line so it can be told apart from regular output.
+const (
+	markerPrefix  = "@@gosysmesh:"
+	sectionMarker = markerPrefix + "section:"
+	statusMarker  = markerPrefix + "status:"
+	stderrMarker  = markerPrefix + "stderr:"
+)
+
+// Names of the sections produced by the collect commands. The stats section
+// holds both the system and the disk sections' output.
+const (
+	sectionProcesses = "ps"
+	sectionStats     = "stats"
+	sectionSystem    = "system"
+	sectionDisk      = "disk"
+)
+
+// scriptSection wraps command so that its output is preceded by a header for
+// name, followed by its exit status, and its stderr lines are tagged.
+func scriptSection(name, command string) string {
+	return "echo " + sectionMarker + name + "; " +
+		"{ { " + command + "; printf '\\n" + statusMarker + "%s\\n' $?; } 2>&1 1>&3 | sed 's/^/" + stderrMarker + "/'; } 3>&1"
+}
+
+// section is the decoded output of one part of the collection script.
+type section struct {
+	Stdout string
+	Stderr string
+	Status int
+}
+
+// splitSections decodes the output of the combined collection script. A
+// section whose exit status is missing, e.g. because the output was cut
+// short, is reported as an error.
+func splitSections(output string) (map[string]*section, error) {
+	sections := make(map[string]*section)
+	var (
+		current *section
+		name    string
+		stdout  []string
+		stderr  []string
+		done    bool
+	)
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		if !done {
+			return fmt.Errorf("section %q has no exit status", name)
+		}
+		current.Stdout = strings.Join(stdout, "\n")
+		current.Stderr = strings.Join(stderr, "\n")
+		sections[name] = current
+		return nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, sectionMarker):
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			name = strings.TrimPrefix(line, sectionMarker)
+			current, stdout, stderr, done = &section{}, nil, nil, false
+		case current == nil, line == "":
+			// Anything before the first section, such as a login banner, is
+			// ignored, as are the blank lines that separate the status markers
+		case strings.HasPrefix(line, statusMarker):
+			status, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, statusMarker)))
+			if err != nil {
+				return nil, fmt.Errorf("section %q has invalid exit status: %w", name, err)
+			}
+			current.Status, done = status, true
+		case strings.HasPrefix(line, stderrMarker):
+			stderr = append(stderr, strings.TrimPrefix(line, stderrMarker))
+		default:
+			// A stderr line can land after stdout that does not end in a
+			// newline, since the two reach us through different pipes
+			if i := strings.Index(line, stderrMarker); i > 0 {
+				stdout = append(stdout, line[:i])
+				stderr = append(stderr, line[i+len(stderrMarker):])
+				continue
+			}
+			stdout = append(stdout, line)
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
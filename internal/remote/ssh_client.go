@@ -2,155 +2,276 @@ package remote
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/ChristianThibeault/gosysmesh/internal/validation"
 )
 
-// RunSSHCommandOpenSSH executes a command over SSH via the OpenSSH client with ProxyJump support
-func RunSSHCommandOpenSSH(user, host string, port int, keyPath, proxyJump, command string) (string, error) {
-	// Input validation
-	if err := validateSSHParams(user, host, keyPath); err != nil {
-		return "", fmt.Errorf("invalid SSH parameters: %w", err)
-	}
+// RunSSHCommandOpenSSH executes the registered command id with args on
+// target over SSH via the OpenSSH client, reaching it through the target's
+// jump host chain if one is configured.
+func RunSSHCommandOpenSSH(target config.RemoteTarget, id CommandID, args ...string) (string, error) {
+	return RunSSHCommandOpenSSHInput(target, nil, id, args...)
+}
 
-	keyPath = expandTilde(os.ExpandEnv(keyPath))
-	args := []string{
-		"-i", keyPath,
-		"-p", fmt.Sprintf("%d", port),
-		fmt.Sprintf("%s@%s", user, host),
-		"-o", "ConnectTimeout=10",
-		"-o", "ServerAliveInterval=30",
-		"-o", "ServerAliveCountMax=3",
-		// Enable host key checking for security
-		"-o", "StrictHostKeyChecking=yes",
+// RunSSHCommandOpenSSHInput is like RunSSHCommandOpenSSH but feeds stdin to
+// the remote command. A nil stdin reads as empty input on the remote side.
+func RunSSHCommandOpenSSHInput(target config.RemoteTarget, stdin io.Reader, id CommandID, args ...string) (string, error) {
+	command, err := BuildCommand(id, args...)
+	if err != nil {
+		return "", fmt.Errorf("invalid command: %w", err)
 	}
-
-	if proxyJump != "" {
-		if err := validateHostname(proxyJump); err != nil {
-			return "", fmt.Errorf("invalid proxy jump host: %w", err)
+	sshArgs, err := buildSSHArgs(target, command)
+	if err != nil {
+		return "", err
+	}
+	if target.KeyPolicy() == config.HostKeyPinned {
+		if err := checkPinnedHostKey(target); err != nil {
+			return "", err
 		}
-		// Use configurable port instead of hardcoded 5822
-		proxy := fmt.Sprintf("%s@%s", user, proxyJump)
-		args = append([]string{"-J", proxy}, args...)
 	}
 
-	// Validate and sanitize command before execution
-	if err := validateCommand(command); err != nil {
-		return "", fmt.Errorf("invalid command: %w", err)
-	}
-	args = append(args, command)
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
 
-	cmd := exec.Command("ssh", args...)
+	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
+	if target.IdentityAgent != "" {
+		// Jump hosts use the configured agent too
+		cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+expandPath(target.IdentityAgent))
+	}
 	var stdout, stderr bytes.Buffer
+	sent := &countingReader{r: stdin}
+	if stdin != nil {
+		cmd.Stdin = sent
+	}
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	run := commandRun{target: target, id: id, command: command, start: time.Now(), exitStatus: -1}
+	err = cmd.Run()
+	run.duration = time.Since(run.start)
+	if cmd.ProcessState != nil {
+		run.exitStatus = cmd.ProcessState.ExitCode()
+	}
+	run.bytesSent, run.bytesOut = sent.n, int64(stdout.Len())
 	if err != nil {
-		return "", fmt.Errorf("ssh error: %v — stderr: %s", err, stderr.String())
+		run.err = classifySSHError(target.Host, err, stderr.String(), ctx.Err())
+	}
+
+	// A command that cannot be audited is treated as failed
+	if auditErr := recordAudit(run); auditErr != nil {
+		return "", fmt.Errorf("failed to write audit log: %w", auditErr)
+	}
+	if run.err != nil {
+		return "", run.err
 	}
 	return stdout.String(), nil
 }
 
-// expandTilde replaces ~ with home directory
-func expandTilde(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		if home, err := os.UserHomeDir(); err == nil {
-			return home + path[1:]
-		}
+// commandTimeout bounds a whole SSH invocation, including connection setup.
+const commandTimeout = 60 * time.Second
+
+// sshOptions are applied to the target connection and to every jump hop.
+var sshOptions = []string{
+	"-o", "ConnectTimeout=10",
+	"-o", "ServerAliveInterval=30",
+	"-o", "ServerAliveCountMax=3",
+	// Fail instead of prompting for passwords or passphrases
+	"-o", "BatchMode=yes",
+}
+
+// strictHostKeyOptions only accept hosts whose key is in the user's
+// known_hosts.
+var strictHostKeyOptions = []string{"-o", "StrictHostKeyChecking=yes"}
+
+// hostKeyOptions returns the ssh options enforcing policy. The tofu and
+// pinned policies check keys against the gosysmesh-managed known_hosts only;
+// tofu also records the key of hosts it does not know yet.
+func hostKeyOptions(policy string) ([]string, error) {
+	if policy != config.HostKeyTOFU && policy != config.HostKeyPinned {
+		return strictHostKeyOptions, nil
 	}
-	return path
+	path, err := ManagedKnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	checking := "yes"
+	if policy == config.HostKeyTOFU {
+		checking = "accept-new"
+	}
+	return []string{
+		"-o", "StrictHostKeyChecking=" + checking,
+		"-o", "UserKnownHostsFile=" + path,
+		"-o", "HashKnownHosts=no",
+	}, nil
 }
 
-// validateSSHParams validates SSH connection parameters
-func validateSSHParams(user, host, keyPath string) error {
-	if user == "" {
-		return errors.New("user cannot be empty")
+// hopHostKeyOptions returns the host key options for the jump hosts of
+// target, or nil if the user's defaults apply. Pinned fingerprints only
+// describe the target itself, so its hops are checked strictly.
+func hopHostKeyOptions(target config.RemoteTarget) ([]string, error) {
+	if target.KeyPolicy() != config.HostKeyTOFU {
+		return nil, nil
+	}
+	return hostKeyOptions(config.HostKeyTOFU)
+}
+
+// buildSSHArgs validates the target and returns the arguments to pass to
+// ssh(1) to run command, which must come from BuildCommand.
+func buildSSHArgs(target config.RemoteTarget, command string) ([]string, error) {
+	// Input validation
+	if err := validateSSHParams(target.User, target.Host); err != nil {
+		return nil, fmt.Errorf("invalid SSH parameters: %w", err)
 	}
-	if host == "" {
-		return errors.New("host cannot be empty")
+	if target.SSHKey == "" && target.AgentSocket() == "" {
+		return nil, fmt.Errorf("invalid SSH parameters: %w", errNoAgent)
 	}
-	if keyPath == "" {
-		return errors.New("SSH key path cannot be empty")
+
+	chain, err := target.JumpChain()
+	if err != nil {
+		return nil, fmt.Errorf("invalid jump hosts: %w", err)
+	}
+	for i, hop := range chain {
+		if err := validateJumpHop(hop); err != nil {
+			return nil, fmt.Errorf("invalid jump host: %w", validation.Field(fmt.Sprintf("jump_hosts[%d]", i), err))
+		}
 	}
 
-	// Validate username format (alphanumeric, underscore, dash)
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(user) {
-		return errors.New("invalid username format")
+	keyOpts, err := hostKeyOptions(target.KeyPolicy())
+	if err != nil {
+		return nil, err
+	}
+	hopOpts, err := hopHostKeyOptions(target)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate hostname/IP format
-	if err := validateHostname(host); err != nil {
-		return fmt.Errorf("invalid host: %w", err)
+	// A key with a configured passphrase is unlocked into the in-process
+	// agent, as ssh cannot prompt for it
+	var agentSocket string
+	if target.HasPassphrase() {
+		if agentSocket, err = unlockKey(target); err != nil {
+			return nil, &Error{Class: ClassAuth, Host: target.Host, Err: err}
+		}
 	}
 
-	return nil
+	args := authArgs(target, agentSocket)
+	args = append(args, "-p", strconv.Itoa(target.Port))
+	args = append(args, sshOptions...)
+	args = append(args, keyOpts...)
+	args = append(args, jumpArgs(chain, hopOpts, target.Host)...)
+	args = append(args, fmt.Sprintf("%s@%s", target.User, target.Host), command)
+	return args, nil
 }
 
-// validateHostname validates hostname or IP address format
-func validateHostname(host string) error {
-	if host == "" {
-		return errors.New("hostname cannot be empty")
+// jumpArgs returns the ssh arguments that route a connection to dest through
+// chain, checking the hops' host keys with hopOpts or, if nil, strictly. ssh's -J
+// cannot give hops their own identity file or options, so when any hop sets
+// a key or hopOpts is set the chain is expressed as nested ProxyCommands
+// instead.
+func jumpArgs(chain []config.JumpConfig, hopOpts []string, dest string) []string {
+	if len(chain) == 0 {
+		return nil
 	}
 
-	// Allow hostname format (RFC 1123) or IP address
-	hostnameRegex := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
-	ipRegex := regexp.MustCompile(`^(?:[0-9]{1,3}\.){3}[0-9]{1,3}$`)
-
-	if !hostnameRegex.MatchString(host) && !ipRegex.MatchString(host) {
-		return errors.New("invalid hostname or IP address format")
+	withKeys := false
+	for _, hop := range chain {
+		if hop.SSHKeyPath != "" {
+			withKeys = true
+		}
 	}
 
-	return nil
+	if !withKeys && hopOpts == nil {
+		hops := make([]string, len(chain))
+		for i, hop := range chain {
+			hops[i] = hop.User + "@" + net.JoinHostPort(hop.Host, strconv.Itoa(hop.Port))
+		}
+		return []string{"-J", strings.Join(hops, ",")}
+	}
+	return []string{"-o", "ProxyCommand=" + proxyCommand(chain, hopOpts, dest)}
 }
 
-// validateCommand validates command for basic security
-func validateCommand(command string) error {
-	if command == "" {
-		return errors.New("command cannot be empty")
+// proxyCommand builds the ProxyCommand that opens a stdio tunnel to dest
+// through the last hop of chain, itself reached through the earlier hops.
+// Each nested command is shell-quoted, and its % tokens escaped, because it
+// is expanded once by every ssh process that encloses it.
+func proxyCommand(chain []config.JumpConfig, hopOpts []string, dest string) string {
+	if hopOpts == nil {
+		hopOpts = strictHostKeyOptions
 	}
+	hop := chain[len(chain)-1]
 
-	// Block dangerous injection patterns while allowing legitimate shell operators
-	dangerousPatterns := []string{
-		"$(", "`", "\n", "\r", ";rm", ";wget", ";curl", ";sh", ";bash",
-		"&&rm", "&&wget", "&&curl", "&&sh", "&&bash",
-		"||rm", "||wget", "||curl", "||sh", "||bash",
+	parts := []string{"ssh"}
+	if hop.SSHKeyPath != "" {
+		parts = append(parts, "-i", shellQuote(expandTilde(os.ExpandEnv(hop.SSHKeyPath))))
 	}
+	parts = append(parts, "-p", strconv.Itoa(hop.Port))
+	parts = append(parts, sshOptions...)
+	parts = append(parts, hopOpts...)
+	if len(chain) > 1 {
+		inner := strings.ReplaceAll(proxyCommand(chain[:len(chain)-1], hopOpts, hop.Host), "%", "%%")
+		parts = append(parts, "-o", shellQuote("ProxyCommand="+inner))
+	}
+	parts = append(parts, "-W", forwardSpec(dest), shellQuote(hop.User+"@"+escapeTokens(hop.Host)))
+	return strings.Join(parts, " ")
+}
 
-	commandLower := strings.ToLower(command)
-	for _, pattern := range dangerousPatterns {
-		if strings.Contains(commandLower, pattern) {
-			return fmt.Errorf("command contains dangerous pattern: %s", pattern)
-		}
+// forwardSpec returns the -W argument forwarding to dest on the port ssh
+// substitutes for %p. IPv6 addresses are bracketed so their colons are not
+// taken for the port separator.
+func forwardSpec(dest string) string {
+	if validation.IsIPv6(dest) {
+		return "[%h]:%p"
 	}
+	return "%h:%p"
+}
 
-	return nil
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
-// BuildPsCommand safely constructs a ps command with validated user parameter
-func BuildPsCommand(user string) (string, error) {
-	if err := validateUsername(user); err != nil {
-		return "", fmt.Errorf("invalid user for ps command: %w", err)
+// expandTilde replaces ~ with home directory
+func expandTilde(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
 	}
-	return fmt.Sprintf("ps -u %s -o pid,user,%%cpu,%%mem,stat,lstart,args --no-headers", user), nil
+	return path
 }
 
-// BuildSystemStatsCommand returns the pre-approved system stats command
-func BuildSystemStatsCommand() string {
-	return `top -bn1 | grep "Cpu(s)" | awk '{print $2}' | sed 's/%us,//'; free -m | awk 'NR==2{printf "%.0f %.0f", $3,$2}'; df -h / | awk 'NR==2{gsub(/[^0-9.]/, "", $3); gsub(/[^0-9.]/, "", $2); printf " %.1f %.1f", $3, $2}'`
+// validateSSHParams validates SSH connection parameters
+func validateSSHParams(user, host string) error {
+	if err := validation.Username(user); err != nil {
+		return validation.Field("user", err)
+	}
+	return validation.Field("host", validation.Host(host))
 }
 
-// validateUsername validates username for command construction
-func validateUsername(user string) error {
-	if user == "" {
-		return errors.New("username cannot be empty")
+// validateJumpHop validates a single jump host
+func validateJumpHop(hop config.JumpConfig) error {
+	if err := validation.Username(hop.User); err != nil {
+		return validation.Field("user", err)
+	}
+	if err := validation.Host(hop.Host); err != nil {
+		return validation.Field("host", err)
+	}
+	if hop.Port < 1 || hop.Port > 65535 {
+		return validation.Field("port", errors.New("port must be between 1 and 65535"))
 	}
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(user) {
-		return errors.New("invalid username format")
+	if strings.ContainsAny(hop.SSHKeyPath, "\x00\n\r") {
+		return validation.Field("ssh_key", errors.New("SSH key path contains dangerous characters"))
 	}
 	return nil
 }
@@ -0,0 +1,92 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/ChristianThibeault/gosysmesh/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSSHArgsJumpChain(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:      "db1.internal",
+		User:      "monitor",
+		Port:      22,
+		SSHKey:    "/keys/monitor",
+		ProxyJump: "ops@bastion1:2222,bastion2",
+	}
+
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "-J")
+	assert.Contains(t, args, "ops@bastion1:2222,monitor@bastion2:22")
+	assert.Equal(t, []string{"monitor@db1.internal", "uptime"}, args[len(args)-2:])
+}
+
+func TestBuildSSHArgsIPv6(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:      "2001:db8::10",
+		User:      "monitor",
+		Port:      22,
+		SSHKey:    "/keys/monitor",
+		ProxyJump: "ops@[2001:db8::1]:2222,fe80::1%eth0",
+	}
+
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "ops@[2001:db8::1]:2222,monitor@[fe80::1%eth0]:22")
+	assert.Equal(t, []string{"monitor@2001:db8::10", "uptime"}, args[len(args)-2:])
+
+	target.JumpHosts = []config.JumpConfig{{Host: "fe80::1%eth0", User: "ops", Port: 22, SSHKeyPath: "/keys/ops"}}
+	target.ProxyJump = ""
+	args, err = buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.Contains(t, args, "ProxyCommand=ssh -i '/keys/ops' -p 22 "+optionString()+" -W [%h]:%p 'ops@fe80::1%%eth0'")
+}
+
+func TestBuildSSHArgsJumpHostsWithKeys(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:   "db1.internal",
+		User:   "monitor",
+		Port:   22,
+		SSHKey: "/keys/monitor",
+		JumpHosts: []config.JumpConfig{
+			{Host: "bastion1", User: "ops", Port: 2222, SSHKeyPath: "/keys/ops"},
+			{Host: "bastion2", User: "jump"},
+		},
+	}
+
+	args, err := buildSSHArgs(target, "uptime")
+	require.NoError(t, err)
+	assert.NotContains(t, args, "-J")
+
+	expected := "ProxyCommand=ssh -p 22 " + optionString() +
+		` -o 'ProxyCommand=ssh -i '\''/keys/ops'\'' -p 2222 ` + optionString() +
+		` -W %%h:%%p '\''ops@bastion1'\''' -W %h:%p 'jump@bastion2'`
+	assert.Contains(t, args, expected)
+}
+
+func TestBuildSSHArgsRejectsInvalidHop(t *testing.T) {
+	target := config.RemoteTarget{
+		Host:      "db1.internal",
+		User:      "monitor",
+		Port:      22,
+		SSHKey:    "/keys/monitor",
+		JumpHosts: []config.JumpConfig{{Host: "bad host", User: "ops", Port: 22}},
+	}
+
+	_, err := buildSSHArgs(target, "uptime")
+	assert.Error(t, err)
+}
+
+func optionString() string {
+	s := ""
+	for i, opt := range append(append([]string{}, sshOptions...), strictHostKeyOptions...) {
+		if i > 0 {
+			s += " "
+		}
+		s += opt
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+// Package schedule decides when each collection job runs. Every job has its
+// own interval, and each run is delayed by a random jitter so that targets
+// sharing an interval do not all open their SSH connections at once. Jobs of
+// one group that share an interval share each run's jitter too, so that they
+// fall due together and can be collected in a single round-trip.
+//
+// A Scheduler does not run anything itself: the caller waits until Next,
+// then runs the jobs returned by Due.
+package schedule
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Job is a collection to repeat every Interval, each run delayed by up to
+// Jitter after its slot.
+type Job struct {
+	// Key identifies the job across calls to Set.
+	Key string
+	// Group names jobs that run together, such as the metric families of
+	// one target. Jobs of a group with the same interval always fall due at
+	// the same time. An empty group runs the job on its own.
+	Group    string
+	Interval time.Duration
+	Jitter   time.Duration
+}
+
+// entry is the schedule of the jobs of one group and interval.
+type entry struct {
+	jobs []Job     // sorted by key
+	slot time.Time // when the run is due before jitter
+	next time.Time // slot plus this run's jitter
+}
+
+// Scheduler tracks when each job runs next.
+type Scheduler struct {
+	entries map[string]*entry
+	rand    *rand.Rand
+}
+
+// New returns an empty scheduler drawing jitter from rnd, or from a source
+// seeded with the current time if rnd is nil.
+func New(rnd *rand.Rand) *Scheduler {
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &Scheduler{entries: make(map[string]*entry), rand: rnd}
+}
+
+// entryKey returns the key of the entry that runs job.
+func entryKey(job Job) string {
+	if job.Group == "" {
+		return "job\x00" + job.Key
+	}
+	return "group\x00" + job.Group + "\x00" + job.Interval.String()
+}
+
+// Set replaces the scheduled jobs with jobs. Jobs not scheduled before
+// first run within their jitter of now; jobs already scheduled keep their
+// next run, with a changed interval applying from the run after it.
+func (s *Scheduler) Set(jobs []Job, now time.Time) {
+	previous := make(map[string]*entry)
+	for _, e := range s.entries {
+		for _, job := range e.jobs {
+			previous[job.Key] = e
+		}
+	}
+
+	entries := make(map[string]*entry)
+	var added []*entry
+	for _, job := range jobs {
+		key := entryKey(job)
+		e, ok := entries[key]
+		if !ok {
+			e = &entry{}
+			switch old, kept := s.entries[key], previous[job.Key]; {
+			case old != nil:
+				e.slot, e.next = old.slot, old.next
+			case kept != nil:
+				e.slot, e.next = kept.slot, kept.next
+			default:
+				e.slot = now
+				added = append(added, e)
+			}
+			entries[key] = e
+		}
+		e.jobs = append(e.jobs, job)
+	}
+	for _, e := range entries {
+		sort.Slice(e.jobs, func(i, j int) bool { return e.jobs[i].Key < e.jobs[j].Key })
+	}
+	// Drawn in the order of jobs so that a seeded source gives the same schedule
+	for _, e := range added {
+		e.next = now.Add(s.jitter(maxJitter(e.jobs)))
+	}
+	s.entries = entries
+}
+
+// Len returns the number of scheduled jobs.
+func (s *Scheduler) Len() int {
+	n := 0
+	for _, e := range s.entries {
+		n += len(e.jobs)
+	}
+	return n
+}
+
+// Next returns when the earliest job is due, and false if no job is
+// scheduled.
+func (s *Scheduler) Next() (time.Time, bool) {
+	var next time.Time
+	for _, e := range s.entries {
+		if next.IsZero() || e.next.Before(next) {
+			next = e.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Due returns the jobs due at now, earliest first, and schedules their next
+// run one interval after the current slot. Slots missed because a run took
+// too long are skipped rather than run in a burst.
+func (s *Scheduler) Due(now time.Time) []Job {
+	var due []*entry
+	for _, e := range s.entries {
+		if !e.next.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].next.Equal(due[j].next) {
+			return due[i].next.Before(due[j].next)
+		}
+		return due[i].jobs[0].Key < due[j].jobs[0].Key
+	})
+
+	var jobs []Job
+	for _, e := range due {
+		jobs = append(jobs, e.jobs...)
+		// Jobs of an entry share their interval
+		interval := e.jobs[0].Interval
+		e.slot = e.slot.Add(interval)
+		if !e.slot.After(now) {
+			e.slot = now.Add(interval)
+		}
+		e.next = e.slot.Add(s.jitter(maxJitter(e.jobs)))
+	}
+	return jobs
+}
+
+// maxJitter returns the largest jitter of jobs.
+func maxJitter(jobs []Job) time.Duration {
+	var max time.Duration
+	for _, job := range jobs {
+		if job.Jitter > max {
+			max = job.Jitter
+		}
+	}
+	return max
+}
+
+// jitter draws the delay of one run, up to max.
+func (s *Scheduler) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(s.rand.Int63n(int64(max)))
+}
@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func keys(jobs []Job) []string {
+	var result []string
+	for _, job := range jobs {
+		result = append(result, job.Key)
+	}
+	return result
+}
+
+// entryOf returns the entry running the job with key.
+func entryOf(s *Scheduler, key string) *entry {
+	for _, e := range s.entries {
+		for _, job := range e.jobs {
+			if job.Key == key {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func TestSchedulerIntervals(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)))
+	s.Set([]Job{
+		{Key: "db/processes", Interval: 5 * time.Second},
+		{Key: "web/system", Interval: 30 * time.Second},
+	}, start)
+
+	next, ok := s.Next()
+	require.True(t, ok)
+	assert.Equal(t, start, next, "without jitter new jobs run right away")
+	assert.Equal(t, []string{"db/processes", "web/system"}, keys(s.Due(start)))
+
+	// Only the five-second job is due until the thirty-second mark
+	var runs int
+	for now := start.Add(time.Second); now.Before(start.Add(30 * time.Second)); now = now.Add(time.Second) {
+		for _, job := range s.Due(now) {
+			assert.Equal(t, "db/processes", job.Key)
+			runs++
+		}
+	}
+	assert.Equal(t, 5, runs)
+	assert.Equal(t, []string{"db/processes", "web/system"}, keys(s.Due(start.Add(30*time.Second))))
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)))
+	var jobs []Job
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		jobs = append(jobs, Job{Key: key, Interval: time.Minute, Jitter: 10 * time.Second})
+	}
+	s.Set(jobs, start)
+
+	firstRuns := map[time.Time]bool{}
+	for _, e := range s.entries {
+		assert.False(t, e.next.Before(start))
+		assert.True(t, e.next.Before(start.Add(10*time.Second)))
+		firstRuns[e.next] = true
+	}
+	assert.Greater(t, len(firstRuns), 1, "jitter spreads the first runs")
+
+	s.Due(start.Add(10 * time.Second))
+	for _, e := range s.entries {
+		assert.Equal(t, start.Add(time.Minute), e.slot, "jitter does not accumulate")
+		assert.False(t, e.next.Before(e.slot))
+		assert.True(t, e.next.Before(e.slot.Add(10*time.Second)))
+	}
+}
+
+func TestSchedulerSkipsMissedSlots(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)))
+	s.Set([]Job{{Key: "slow", Interval: 5 * time.Second}}, start)
+	s.Due(start)
+
+	// A run that overran three slots is followed by a single run, not a burst
+	late := start.Add(17 * time.Second)
+	assert.Len(t, s.Due(late), 1)
+	assert.Empty(t, s.Due(late))
+	next, _ := s.Next()
+	assert.Equal(t, late.Add(5*time.Second), next)
+}
+
+func TestSchedulerSet(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)))
+	s.Set([]Job{{Key: "kept", Interval: time.Minute}, {Key: "removed", Interval: time.Minute}}, start)
+	s.Due(start)
+
+	later := start.Add(20 * time.Second)
+	s.Set([]Job{{Key: "kept", Interval: 2 * time.Minute}, {Key: "added", Interval: time.Minute}}, later)
+	assert.Equal(t, 2, s.Len())
+
+	assert.Equal(t, []string{"added"}, keys(s.Due(later)))
+	assert.Equal(t, start.Add(time.Minute), entryOf(s, "kept").next, "a kept job keeps its next run")
+
+	s.Due(start.Add(time.Minute))
+	assert.Equal(t, start.Add(3*time.Minute), entryOf(s, "kept").next, "the new interval applies after it")
+
+	s.Set(nil, later)
+	_, ok := s.Next()
+	assert.False(t, ok)
+}
+
+func TestSchedulerGroups(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)))
+	var jobs []Job
+	for _, target := range []string{"db1", "db2", "web1"} {
+		for _, family := range []string{"processes", "system", "disk"} {
+			interval := 30 * time.Second
+			if target == "web1" && family == "disk" {
+				interval = 5 * time.Minute
+			}
+			jobs = append(jobs, Job{Key: target + "/" + family, Group: target, Interval: interval, Jitter: 3 * time.Second})
+		}
+	}
+	s.Set(jobs, start)
+	require.Equal(t, 9, s.Len())
+
+	// Families of a target sharing an interval share every run's jitter
+	for now := start; now.Before(start.Add(5 * time.Minute)); now = now.Add(100 * time.Millisecond) {
+		due := map[string][]string{}
+		for _, job := range s.Due(now) {
+			due[job.Group] = append(due[job.Group], job.Key)
+		}
+		for group, keys := range due {
+			switch {
+			case group != "web1":
+				assert.Equal(t, []string{group + "/disk", group + "/processes", group + "/system"}, keys)
+			case len(keys) != 1:
+				assert.Equal(t, []string{"web1/processes", "web1/system"}, keys)
+			}
+		}
+	}
+
+	assert.Same(t, entryOf(s, "db1/processes"), entryOf(s, "db1/disk"))
+	assert.NotSame(t, entryOf(s, "db1/processes"), entryOf(s, "db2/processes"), "targets are still spread out")
+	assert.NotSame(t, entryOf(s, "web1/system"), entryOf(s, "web1/disk"))
+
+	// Kept on reload, even when a family moves to another interval
+	next := entryOf(s, "db1/system").next
+	jobs[2].Interval = time.Minute
+	s.Set(jobs, start.Add(5*time.Minute))
+	assert.Equal(t, next, entryOf(s, "db1/system").next)
+	assert.Equal(t, next, entryOf(s, "db1/disk").next)
+}
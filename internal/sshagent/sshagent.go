@@ -0,0 +1,152 @@
+// Package sshagent serves an in-process ssh-agent on a private Unix socket,
+// so that OpenSSH can use keys gosysmesh unlocked itself without prompting,
+// and inspects agents for diagnostics.
+package sshagent
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Agent is an ssh-agent holding keys in memory, reachable at Socket.
+type Agent struct {
+	keyring  agent.Agent
+	dir      string
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// Start creates the agent and starts serving it on a socket in a new
+// directory only the current user can access.
+func Start() (*Agent, error) {
+	dir, err := os.MkdirTemp("", "gosysmesh-agent-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent directory: %w", err)
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on agent socket: %w", err)
+	}
+
+	a := &Agent{keyring: agent.NewKeyring(), dir: dir, listener: l}
+	a.wg.Add(1)
+	go a.serve()
+	return a, nil
+}
+
+func (a *Agent) serve() {
+	defer a.wg.Done()
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(a.keyring, conn)
+		}()
+	}
+}
+
+// Socket returns the path of the agent's socket, for SSH_AUTH_SOCK or
+// IdentityAgent.
+func (a *Agent) Socket() string {
+	return a.listener.Addr().String()
+}
+
+// Keyring returns the agent's keys, for adding to them directly.
+func (a *Agent) Keyring() agent.Agent {
+	return a.keyring
+}
+
+// AddKey decrypts the private key at keyPath with passphrase and adds it to
+// the agent, along with the OpenSSH certificate at certPath if that is not
+// empty.
+func (a *Agent) AddKey(keyPath string, passphrase []byte, certPath string) error {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key: %w", err)
+	}
+	var key any
+	if len(passphrase) == 0 {
+		key, err = ssh.ParseRawPrivateKey(pemBytes)
+	} else {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, passphrase)
+	}
+	if errors.Is(err, x509.IncorrectPasswordError) || errors.As(err, new(*ssh.PassphraseMissingError)) {
+		return fmt.Errorf("failed to decrypt %s: wrong or missing passphrase", keyPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", keyPath, err)
+	}
+
+	added := agent.AddedKey{PrivateKey: key, Comment: keyPath}
+	if certPath != "" {
+		cert, err := readCertificate(certPath)
+		if err != nil {
+			return err
+		}
+		added.Certificate = cert
+	}
+	if err := a.keyring.Add(added); err != nil {
+		return fmt.Errorf("failed to add %s to the agent: %w", keyPath, err)
+	}
+	// The agent lists a key with a certificate only as the certificate; add
+	// it plainly too so hosts not trusting the CA can still accept it
+	if added.Certificate != nil {
+		added.Certificate = nil
+		if err := a.keyring.Add(added); err != nil {
+			return fmt.Errorf("failed to add %s to the agent: %w", keyPath, err)
+		}
+	}
+	return nil
+}
+
+// readCertificate parses the OpenSSH certificate at path.
+func readCertificate(path string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is a public key, not a certificate", path)
+	}
+	return cert, nil
+}
+
+// Close stops serving the agent, forgets its keys and removes its socket.
+func (a *Agent) Close() error {
+	err := a.listener.Close()
+	a.keyring.RemoveAll()
+	a.wg.Wait()
+	os.RemoveAll(a.dir)
+	return err
+}
+
+// List returns the keys held by the agent listening at socket.
+func List(socket string) ([]*agent.Key, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach agent: %w", err)
+	}
+	defer conn.Close()
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent keys: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,102 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeKey writes a new ed25519 key encrypted with passphrase and returns its
+// path and public key.
+func writeKey(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "test", []byte(passphrase))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return path, sshPub
+}
+
+// writeCertificate signs key with a new CA and writes the certificate.
+func writeCertificate(t *testing.T, key ssh.PublicKey) string {
+	t.Helper()
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ca, err := ssh.NewSignerFromKey(caKey)
+	require.NoError(t, err)
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.UserCert,
+		KeyId:           "monitor",
+		ValidPrincipals: []string{"monitor"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+	path := filepath.Join(t.TempDir(), "id_ed25519-cert.pub")
+	require.NoError(t, os.WriteFile(path, ssh.MarshalAuthorizedKey(cert), 0o644))
+	return path
+}
+
+func TestAddKey(t *testing.T) {
+	keyPath, pub := writeKey(t, "correct horse")
+	a, err := Start()
+	require.NoError(t, err)
+	defer a.Close()
+
+	err = a.AddKey(keyPath, []byte("wrong"), "")
+	assert.ErrorContains(t, err, "wrong or missing passphrase")
+	err = a.AddKey(keyPath, nil, "")
+	assert.ErrorContains(t, err, "wrong or missing passphrase")
+
+	require.NoError(t, a.AddKey(keyPath, []byte("correct horse"), ""))
+	keys, err := List(a.Socket())
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, pub.Marshal(), keys[0].Blob)
+	assert.Equal(t, keyPath, keys[0].Comment)
+}
+
+func TestAddKeyWithCertificate(t *testing.T) {
+	keyPath, pub := writeKey(t, "secret")
+	certPath := writeCertificate(t, pub)
+	a, err := Start()
+	require.NoError(t, err)
+	defer a.Close()
+
+	require.NoError(t, a.AddKey(keyPath, []byte("secret"), certPath))
+	keys, err := List(a.Socket())
+	require.NoError(t, err)
+	var types []string
+	for _, k := range keys {
+		types = append(types, k.Format)
+	}
+	assert.ElementsMatch(t, []string{ssh.CertAlgoED25519v01, ssh.KeyAlgoED25519}, types)
+
+	// A plain public key is not a certificate
+	pubPath := filepath.Join(t.TempDir(), "id_ed25519.pub")
+	require.NoError(t, os.WriteFile(pubPath, ssh.MarshalAuthorizedKey(pub), 0o644))
+	assert.ErrorContains(t, a.AddKey(keyPath, []byte("secret"), pubPath), "not a certificate")
+}
+
+func TestClose(t *testing.T) {
+	a, err := Start()
+	require.NoError(t, err)
+	socket := a.Socket()
+	require.NoError(t, a.Close())
+
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err), "socket is removed")
+	_, err = List(socket)
+	assert.ErrorContains(t, err, "cannot reach agent")
+}
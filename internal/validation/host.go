@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// hostnameRegex matches RFC 1123 host names.
+	hostnameRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
+	// numericTLDRegex matches names whose last label is numeric, which must
+	// be IPv4 addresses rather than host names.
+	numericTLDRegex = regexp.MustCompile(`(^|\.)[0-9]+$`)
+	// zoneRegex matches the zone (interface) of a link-local IPv6 address.
+	zoneRegex = regexp.MustCompile(`^[a-zA-Z0-9_.\-]{1,64}$`)
+)
+
+// Host checks that host is a host name, an IPv4 address or an IPv6
+// address, optionally with a zone such as fe80::1%eth0. Addresses are
+// written without brackets; brackets only appear with a port, as in
+// ProxyJump hops.
+func Host(host string) error {
+	if host == "" {
+		return fmt.Errorf("hostname %w", ErrEmpty)
+	}
+	if len(host) > 253 {
+		return errors.New("hostname too long")
+	}
+	if strings.HasPrefix(host, "[") {
+		return errors.New("IPv6 addresses are written without brackets here; set the port separately")
+	}
+
+	if strings.Contains(host, ":") {
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() {
+			return errors.New("invalid IPv6 address")
+		}
+		if zone := addr.Zone(); zone != "" && !zoneRegex.MatchString(zone) {
+			return fmt.Errorf("invalid IPv6 zone %q", zone)
+		}
+		return nil
+	}
+
+	if numericTLDRegex.MatchString(host) {
+		if addr, err := netip.ParseAddr(host); err != nil || !addr.Is4() {
+			return errors.New("invalid IPv4 address")
+		}
+		return nil
+	}
+	if !hostnameRegex.MatchString(host) {
+		return errors.New("invalid hostname or IP address format")
+	}
+	return nil
+}
+
+// IsIPv6 reports whether host is an IPv6 address literal.
+func IsIPv6(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.Is6()
+}
+
+// SplitHostPort splits an OpenSSH style "host", "host:port", "[ipv6]" or
+// "[ipv6]:port" into its host and port, which is zero when absent. A bare
+// IPv6 address cannot carry a port and is returned whole.
+func SplitHostPort(s string) (string, int, error) {
+	var host, port string
+	hasPort := false
+	switch {
+	case strings.HasPrefix(s, "["):
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return "", 0, fmt.Errorf("missing ] in %q", s)
+		}
+		host, port = s[1:end], s[end+1:]
+		if port != "" {
+			if !strings.HasPrefix(port, ":") {
+				return "", 0, fmt.Errorf("unexpected %q after ] in %q", port, s)
+			}
+			port, hasPort = port[1:], true
+		}
+		if !IsIPv6(host) {
+			return "", 0, fmt.Errorf("%q in brackets is not an IPv6 address", host)
+		}
+	case strings.Count(s, ":") > 1:
+		host = s
+	default:
+		host, port, hasPort = strings.Cut(s, ":")
+	}
+
+	if !hasPort {
+		return host, 0, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q", s)
+	}
+	return host, p, nil
+}
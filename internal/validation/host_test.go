@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		wantErr  bool
+	}{
+		{"valid hostname", "example.com", false},
+		{"valid subdomain", "sub.example.com", false},
+		{"valid IP", "192.168.1.1", false},
+		{"valid single word", "localhost", false},
+		{"valid IPv6", "2001:db8::10", false},
+		{"valid IPv6 loopback", "::1", false},
+		{"valid link-local with zone", "fe80::1%eth0", false},
+		{"valid IPv4-mapped IPv6", "::ffff:192.0.2.1", false},
+		{"IPv4 octet out of range", "999.1.1.1", true},
+		{"IPv4 with too few octets", "10.0.1", true},
+		{"bracketed IPv6", "[2001:db8::10]", true},
+		{"IPv6 with port", "2001:db8:0:0:0:0:0:10:22", true},
+		{"invalid IPv6", "2001:db8:::10", true},
+		{"zone with shell characters", "fe80::1%eth0;id", true},
+		{"empty hostname", "", true},
+		{"invalid characters", "host_name", true},
+		{"invalid hostname chars", "host@name", true},
+		{"invalid with space", "host name", true},
+		{"too long", "this-is-a-very-long-hostname-that-exceeds-the-maximum-allowed-length-for-a-hostname-which-should-be-rejected-by-our-validation-function-because-it-is-way-too-long-and-would-cause-problems-in-real-world-usage", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Host(tt.hostname)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Host() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"bastion", "bastion", 0},
+		{"bastion:2222", "bastion", 2222},
+		{"2001:db8::1", "2001:db8::1", 0},
+		{"[2001:db8::1]", "2001:db8::1", 0},
+		{"[fe80::1%eth0]:22", "fe80::1%eth0", 22},
+	}
+	for _, tt := range tests {
+		host, port, err := SplitHostPort(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.host, host, tt.in)
+		assert.Equal(t, tt.port, port, tt.in)
+	}
+
+	for _, in := range []string{"bastion:", "bastion:ssh", "[2001:db8::1", "[2001:db8::1]2222", "[bastion]:22"} {
+		_, _, err := SplitHostPort(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,102 @@
+// Package validation checks the user-supplied values gosysmesh passes to ssh
+// and remote commands: host names and addresses, user names and file paths.
+// The config and remote packages share it so that a value accepted in the
+// configuration is accepted when it is used, and the other way around.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrEmpty is wrapped by the errors of validators given an empty value.
+var ErrEmpty = errors.New("cannot be empty")
+
+// FieldError reports an invalid value at a field path such as
+// monitor.remote[2].user or jump_hosts[0].host.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Field returns err located at path, or nil if err is nil. A FieldError
+// nested in err is flattened, its path appended to path.
+func Field(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if fe, ok := err.(*FieldError); ok {
+		return &FieldError{Path: JoinPath(path, fe.Path), Err: fe.Err}
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+// JoinPath appends the field path child to parent, as in
+// JoinPath("monitor.remote[0]", "user") or JoinPath("users", "[1]").
+func JoinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+// maxUsernameLength is the longest user name useradd accepts.
+const maxUsernameLength = 32
+
+// UsernameChars matches a user name: letters, digits, underscores, dots and
+// dashes, not starting with a dot or dash so it cannot be taken for an
+// option or a hidden path. Patterns embedding user names reuse it.
+const UsernameChars = `[a-zA-Z0-9_][a-zA-Z0-9_.-]*`
+
+var usernameRegex = regexp.MustCompile(`^` + UsernameChars + `$`)
+
+// Username checks that user is a valid login name, such as "monitor" or
+// "first.last".
+func Username(user string) error {
+	if user == "" {
+		return fmt.Errorf("username %w", ErrEmpty)
+	}
+	if len(user) > maxUsernameLength {
+		return fmt.Errorf("username too long (max %d characters)", maxUsernameLength)
+	}
+	if !usernameRegex.MatchString(user) {
+		return errors.New("invalid username format (letters, digits, underscore, dot and dash allowed, not first)")
+	}
+	return nil
+}
+
+// maxPathLength bounds file paths, as PATH_MAX does on Linux.
+const maxPathLength = 4096
+
+// FilePath checks that path is a plausible local file path: not empty, with
+// no parent directory references and no NUL or newline characters.
+func FilePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("file path %w", ErrEmpty)
+	}
+	if len(path) > maxPathLength {
+		return errors.New("file path too long")
+	}
+	if strings.Contains(path, "..") {
+		return errors.New("path traversal not allowed")
+	}
+	if strings.ContainsAny(path, "\x00\n\r") {
+		return errors.New("file path contains dangerous characters")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantErr  bool
+	}{
+		{"valid username", "admin", false},
+		{"valid with underscore", "admin_user", false},
+		{"valid with dash", "admin-user", false},
+		{"valid with numbers", "admin123", false},
+		{"valid with dot", "first.last", false},
+		{"valid machine account", "svc_backup.01", false},
+		{"leading dash", "-oProxyCommand", true},
+		{"leading dot", ".hidden", true},
+		{"empty username", "", true},
+		{"too long", "this_username_is_way_too_long_for_any_system", true},
+		{"invalid characters", "admin@host", true},
+		{"invalid space", "admin user", true},
+		{"invalid special chars", "admin$user", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Username(tt.username)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Username() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"valid path", "/home/user/.ssh/id_rsa", false},
+		{"valid relative", "~/.ssh/id_rsa", false},
+		{"empty path", "", true},
+		{"path traversal", "/home/user/../../../etc/passwd", true},
+		{"null byte", "/home/user/\x00malicious", true},
+		{"newline", "/home/user/file\nname", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FilePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FilePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	assert.NoError(t, Field("user", nil))
+
+	err := Field("monitor.remote[0]", Field("jump_hosts[1]", Field("user", Username(""))))
+	var fe *FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "monitor.remote[0].jump_hosts[1].user", fe.Path)
+	assert.ErrorIs(t, err, ErrEmpty)
+	assert.EqualError(t, err, "monitor.remote[0].jump_hosts[1].user: username cannot be empty")
+
+	assert.Equal(t, "users[2]", JoinPath("users", "[2]"))
+	assert.Equal(t, "user", JoinPath("", "user"))
+}